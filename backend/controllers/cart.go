@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/middlewares"
 	"github.com/BaimhonS/kab-phone/services"
 	"github.com/BaimhonS/kab-phone/validates"
 	"github.com/gofiber/fiber/v2"
@@ -11,9 +12,11 @@ func CartController(app fiber.Router, configClients configs.ConfigClients) {
 	cartController := app.Group("/carts")
 	cartService := services.NewCartService(configClients)
 	cartValidate := validates.NewCartValidate()
+	shippingService := services.NewShippingService(configClients)
 
 	cartController.Get("/", cartService.GetCart)
-	cartController.Post("/items", cartValidate.ValidateAddItemToCart, cartService.AddItemToCart)
-	cartController.Delete("/items/:id", cartService.RemoveItemFromCart)
-	cartController.Patch("/items/:id", cartValidate.ValidateUpdateitemFromCart, cartService.UpdateItemFromCart)
+	cartController.Get("/shipping-quotes", shippingService.GetShippingQuotes)
+	cartController.Post("/items", middlewares.Idempotency(configClients.Redis), cartValidate.ValidateAddItemToCart, cartService.AddItemToCart)
+	cartController.Delete("/items/:id", middlewares.Idempotency(configClients.Redis), middlewares.Ownership(configClients.DB, "item", middlewares.LoadPendingCartItem), cartService.RemoveItemFromCart)
+	cartController.Patch("/items/:id", middlewares.Idempotency(configClients.Redis), cartValidate.ValidateUpdateitemFromCart, middlewares.Ownership(configClients.DB, "item", middlewares.LoadPendingCartItem), cartService.UpdateItemFromCart)
 }
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/services"
+)
+
+func TestGrossMarginLeavesVATOutOfRevenue(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	admin := createTestUser(t, db, "admin", "admin")
+	buyer := createTestUser(t, db, "buyer", "guess")
+	phone := createTestPhone(t, db, 5)
+
+	vat, err := models.ParseRate("7")
+	if err != nil {
+		t.Fatalf("parse rate : %v", err)
+	}
+
+	for _, inclusive := range []bool{true, false} {
+		order, item, _ := createTestOrder(t, db, buyer, phone)
+
+		unitPrice := models.NewMoneyFromBaht(10000)
+		if inclusive {
+			unitPrice = models.NewMoneyFromBaht(10700)
+		}
+
+		if err := db.Model(&models.Item{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+			"unit_price": unitPrice,
+			"unit_cost":  models.NewMoneyFromBaht(8000),
+		}).Error; err != nil {
+			t.Fatalf("update item : %v", err)
+		}
+
+		if err := db.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"vat_rate":      vat,
+			"vat_inclusive": inclusive,
+		}).Error; err != nil {
+			t.Fatalf("update order : %v", err)
+		}
+	}
+
+	status, body := doTestRequest(t, app, http.MethodGet, "/api/orders/gross-margin", admin, nil)
+	if status != http.StatusOK {
+		t.Fatalf("get gross margin : status %d : %s", status, body)
+	}
+
+	var resp struct {
+		Data struct {
+			Phones []services.GrossMargin `json:"phones"`
+			Total  services.GrossMargin   `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("unmarshal gross margin : %v", err)
+	}
+
+	if len(resp.Data.Phones) != 1 {
+		t.Fatalf("%d phones , want 1", len(resp.Data.Phones))
+	}
+
+	margin := resp.Data.Phones[0]
+	if margin.Quantity != 2 || margin.Revenue != models.NewMoneyFromBaht(20000) || margin.Margin != models.NewMoneyFromBaht(4000) {
+		t.Errorf("phone sold %d for %s at a margin of %s , want 2 for 20000.00 at 4000.00", margin.Quantity, margin.Revenue, margin.Margin)
+	}
+
+	if resp.Data.Total.MarginPercent != 20 {
+		t.Errorf("margin percent is %v , want 20", resp.Data.Total.MarginPercent)
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// doIdempotentTestRequest sends a request without a body on behalf of user under the Idempotency-Key.
+func doIdempotentTestRequest(t *testing.T, app *fiber.App, method string, path string, user models.User, idempotencyKey string) (int, http.Header, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(testUserHeader, fmt.Sprint(user.ID))
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s : %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response : %v", err)
+	}
+
+	return resp.StatusCode, resp.Header, respBody
+}
+
+func TestIdempotencyKeyIsBoundToTheURL(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	phone := createTestPhone(t, db, 5)
+	user := createTestUser(t, db, "buyer", "guess")
+	first := addTestCartItem(t, db, user, phone, 1)
+	second := addTestCartItem(t, db, user, phone, 1)
+
+	firstPath := fmt.Sprintf("/api/carts/items/%d", first.ID)
+	if status, _, body := doIdempotentTestRequest(t, app, http.MethodDelete, firstPath, user, "remove-1"); status != http.StatusOK {
+		t.Fatalf("delete first item : status %d : %s", status, body)
+	}
+
+	status, header, body := doIdempotentTestRequest(t, app, http.MethodDelete, firstPath, user, "remove-1")
+	if status != http.StatusOK || header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry delete first item : status %d replayed %q , want %d replayed : %s", status, header.Get("Idempotent-Replayed"), http.StatusOK, body)
+	}
+
+	secondPath := fmt.Sprintf("/api/carts/items/%d", second.ID)
+	if status, _, body := doIdempotentTestRequest(t, app, http.MethodDelete, secondPath, user, "remove-1"); status != http.StatusUnprocessableEntity {
+		t.Errorf("delete second item with the first key : status %d , want %d : %s", status, http.StatusUnprocessableEntity, body)
+	}
+
+	var count int64
+	if err := db.Model(&models.Item{}).Where("id = ?", second.ID).Count(&count).Error; err != nil {
+		t.Fatalf("count items : %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("second item was deleted under the first item's key")
+	}
+}
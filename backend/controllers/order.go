@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/middlewares"
 	"github.com/BaimhonS/kab-phone/services"
 	"github.com/BaimhonS/kab-phone/validates"
 	"github.com/gofiber/fiber/v2"
@@ -11,12 +12,44 @@ func OrderController(app fiber.Router, configClients configs.ConfigClients) {
 	orderController := app.Group("/orders")
 	orderService := services.NewOrderService(configClients)
 	userValidate := validates.NewUserValidate()
+	orderValidate := validates.NewOrderValidate()
+	paymentService := services.NewPaymentService(configClients)
+	paymentValidate := validates.NewPaymentValidate()
+	paymentSlipService := services.NewPaymentSlipService(configClients)
+	returnService := services.NewReturnService(configClients)
+	returnValidate := validates.NewReturnValidate()
+	taxInvoiceService := services.NewTaxInvoiceService(configClients)
+	phoneUnitService := services.NewPhoneUnitService(configClients)
+	phoneUnitValidate := validates.NewPhoneUnitValidate()
 
-	orderController.Post("/confirm", orderService.ConfirmOrder)
+	orderController.Post("/confirm", middlewares.Idempotency(configClients.Redis), orderValidate.ValidateConfirmOrder, orderService.ConfirmOrder)
 	orderController.Get("/track-orders/:tracking_number", orderService.GetOrderByTrackingNumber)
+	orderController.Get("/number/:order_number", orderService.GetOrderByOrderNumber)
+	orderController.Post("/public-tracking", middlewares.PublicRateLimit(configClients.Redis, "public-tracking"), orderValidate.ValidatePublicOrderTracking, orderService.GetPublicOrderTracking)
 	orderController.Get("/track-orders", orderService.GetTrackingNumbers)
 	orderController.Get("/best-worst-phones", orderService.GetBestAndWorstSellingPhones)
 	orderController.Get("/total-income", userValidate.ValidateRoleAdmin, orderService.GetTotalIncome)
+	orderController.Get("/gross-margin", userValidate.ValidateRoleAdmin, orderService.GetGrossMargin)
 	orderController.Get("/check-order", userValidate.ValidateRoleAdmin, orderService.GetAllOrders)
-	orderController.Post("/add-tracking", userValidate.ValidateRoleAdmin, orderService.AddTrackingNumber)
+	orderController.Post("/add-tracking", userValidate.ValidateRoleAdmin, orderValidate.ValidateAddTrackingNumber, orderService.AddTrackingNumber)
+	orderController.Post("/tracking/import", userValidate.ValidateRoleAdmin, orderValidate.ValidateImportTrackingNumbers, orderService.ImportTrackingNumbers)
+	orderController.Patch("/:id/status", userValidate.ValidateRoleAdmin, orderValidate.ValidateUpdateOrderStatus, orderService.UpdateOrderStatus)
+	orderController.Post("/:id/cancel", orderValidate.ValidateCancelOrder, orderService.CancelOrder)
+	orderController.Get("/:id/promptpay", paymentService.GetOrderPromptPay)
+	orderController.Post("/:id/promptpay/receive", userValidate.ValidateRoleAdmin, paymentValidate.ValidateReceivePromptPay, paymentService.ReceiveOrderPromptPay)
+	orderController.Get("/slips", userValidate.ValidateRoleAdmin, paymentSlipService.GetPaymentSlips)
+	orderController.Get("/slips/:id/image", paymentSlipService.GetPaymentSlipImage)
+	orderController.Post("/slips/:id/approve", userValidate.ValidateRoleAdmin, paymentSlipService.ApprovePaymentSlip)
+	orderController.Post("/slips/:id/reject", userValidate.ValidateRoleAdmin, orderValidate.ValidateRejectPaymentSlip, paymentSlipService.RejectPaymentSlip)
+	orderController.Get("/:id/slips", paymentSlipService.GetOrderPaymentSlips)
+	orderController.Post("/:id/slips", orderValidate.ValidateUploadPaymentSlip, paymentSlipService.UploadPaymentSlip)
+	orderController.Get("/:id/returns", returnService.GetOrderReturns)
+	orderController.Post("/:id/returns", returnValidate.ValidateCreateReturn, returnService.CreateReturn)
+	orderController.Get("/:id/receipt.pdf", orderService.GetOrderReceiptPDF)
+	orderController.Post("/:id/tax-invoice", taxInvoiceService.IssueTaxInvoice)
+	orderController.Get("/:id/tax-invoice.pdf", taxInvoiceService.GetTaxInvoicePDF)
+	orderController.Get("/:id/status-histories", userValidate.ValidateRoleAdmin, orderService.GetOrderStatusHistories)
+	orderController.Get("/:id/units", userValidate.ValidateRoleAdmin, phoneUnitService.GetOrderUnits)
+	orderController.Post("/:id/units", userValidate.ValidateRoleAdmin, phoneUnitValidate.ValidateAssignPhoneUnits, phoneUnitService.AssignOrderUnits)
+	orderController.Delete("/:id/units/:imei", userValidate.ValidateRoleAdmin, phoneUnitService.UnassignOrderUnit)
 }
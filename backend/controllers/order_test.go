@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+func TestConfirmOrderParallelDoesNotOversell(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	const (
+		stock  = 3
+		buyers = 10
+	)
+
+	phone := createTestPhone(t, db, stock)
+	createTestCarrier(t, db, "TEST")
+
+	users := make([]models.User, buyers)
+	for i := range users {
+		users[i] = createTestUser(t, db, fmt.Sprintf("buyer%d", i), "guess")
+		addTestCartItem(t, db, users[i], phone, 1)
+	}
+
+	statuses := make([]int, buyers)
+	bodies := make([][]byte, buyers)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range users {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			statuses[i], bodies[i] = doTestRequest(t, app, http.MethodPost, "/api/orders/confirm", users[i], map[string]string{
+				"shipping_carrier": "TEST",
+				"province":         "Bangkok",
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, status := range statuses {
+		switch status {
+		case http.StatusOK:
+			succeeded++
+		case http.StatusConflict:
+		default:
+			t.Errorf("buyer %d : unexpected status %d : %s", i, status, bodies[i])
+		}
+	}
+
+	if succeeded != stock {
+		t.Errorf("%d confirms succeeded , want %d", succeeded, stock)
+	}
+
+	var amount int
+	if err := db.Model(&models.Phone{}).Where("id = ?", phone.ID).Pluck("amount", &amount).Error; err != nil {
+		t.Fatalf("get phone amount : %v", err)
+	}
+
+	if amount != 0 {
+		t.Errorf("phone amount is %d , want 0", amount)
+	}
+
+	var movements []models.StockMovement
+	if err := db.Where("phone_id = ?", phone.ID).Order("id ASC").Find(&movements).Error; err != nil {
+		t.Fatalf("get stock movements : %v", err)
+	}
+
+	ledger := 0
+	sales := 0
+	for _, movement := range movements {
+		ledger += movement.Quantity
+		if movement.BalanceAfter < 0 {
+			t.Errorf("movement %d left the balance at %d", movement.ID, movement.BalanceAfter)
+		}
+		if movement.Type == models.StockMovementSale {
+			sales++
+			if movement.OrderID == nil {
+				t.Errorf("sale movement %d names no order", movement.ID)
+			}
+		}
+	}
+
+	if ledger != amount {
+		t.Errorf("ledger sums to %d , phone amount is %d", ledger, amount)
+	}
+
+	if sales != stock {
+		t.Errorf("%d sale movements , want %d", sales, stock)
+	}
+
+	var orders int64
+	if err := db.Model(&models.Order{}).Count(&orders).Error; err != nil {
+		t.Fatalf("count orders : %v", err)
+	}
+
+	if orders != stock {
+		t.Errorf("%d orders created , want %d", orders, stock)
+	}
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+
+	"gorm.io/gorm"
+)
+
+// createTestOrder places an order for the user from a confirmed cart holding one phone,
+// with a pending payment slip and a requested return.
+func createTestOrder(t *testing.T, db *gorm.DB, user models.User, phone models.Phone) (models.Order, models.Item, models.PaymentSlip) {
+	t.Helper()
+
+	cart := models.Cart{UserId: user.ID, Status: "CONFIRMED"}
+	if err := db.Create(&cart).Error; err != nil {
+		t.Fatalf("create cart : %v", err)
+	}
+
+	item := models.Item{CartID: cart.ID, PhoneID: phone.ID, Amount: 1}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("create item : %v", err)
+	}
+
+	orderNumber, err := utils.GenerateOrderNumber(time.Now().Year())
+	if err != nil {
+		t.Fatalf("generate order number : %v", err)
+	}
+
+	order := models.Order{
+		OrderNumber:    orderNumber,
+		TrackingNumber: fmt.Sprintf("TEST%06d", cart.ID),
+		Status:         models.OrderStatusShipped,
+		CartID:         cart.ID,
+		TotalPrice:     phone.Price,
+	}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("create order : %v", err)
+	}
+
+	slip := models.PaymentSlip{
+		OrderID:      order.ID,
+		UploadedByID: user.ID,
+		Image:        []byte("slip"),
+		ContentType:  "image/png",
+	}
+	if err := db.Create(&slip).Error; err != nil {
+		t.Fatalf("create payment slip : %v", err)
+	}
+
+	if err := db.Create(&models.Return{
+		OrderID:       order.ID,
+		ItemID:        item.ID,
+		Quantity:      1,
+		Resolution:    "REFUND",
+		RequestedByID: user.ID,
+	}).Error; err != nil {
+		t.Fatalf("create return : %v", err)
+	}
+
+	return order, item, slip
+}
+
+func TestCartItemOfAnotherUserIsNotFound(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	phone := createTestPhone(t, db, 5)
+	owner := createTestUser(t, db, "owner", "guess")
+	other := createTestUser(t, db, "other", "guess")
+	item := addTestCartItem(t, db, owner, phone, 1)
+
+	path := fmt.Sprintf("/api/carts/items/%d", item.ID)
+
+	if status, body := doTestRequest(t, app, http.MethodPatch, path, other, map[string]int{"amount": 2}); status != http.StatusNotFound {
+		t.Errorf("update another user's item : status %d , want %d : %s", status, http.StatusNotFound, body)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodDelete, path, other, nil); status != http.StatusNotFound {
+		t.Errorf("delete another user's item : status %d , want %d : %s", status, http.StatusNotFound, body)
+	}
+
+	var stored models.Item
+	if err := db.Where("id = ?", item.ID).First(&stored).Error; err != nil {
+		t.Fatalf("get item : %v", err)
+	}
+
+	if stored.Amount != 1 {
+		t.Errorf("item amount is %d , want 1", stored.Amount)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPatch, path, owner, map[string]int{"amount": 2}); status != http.StatusOK {
+		t.Errorf("update own item : status %d , want %d : %s", status, http.StatusOK, body)
+	}
+}
+
+func TestOrderOfAnotherUserIsNotFound(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	phone := createTestPhone(t, db, 5)
+	owner := createTestUser(t, db, "owner", "guess")
+	other := createTestUser(t, db, "other", "guess")
+	order, _, slip := createTestOrder(t, db, owner, phone)
+
+	paths := []string{
+		fmt.Sprintf("/api/orders/number/%s", order.OrderNumber),
+		fmt.Sprintf("/api/orders/track-orders/%s", order.TrackingNumber),
+		fmt.Sprintf("/api/orders/%d/slips", order.ID),
+		fmt.Sprintf("/api/orders/slips/%d/image", slip.ID),
+		fmt.Sprintf("/api/orders/%d/returns", order.ID),
+	}
+
+	for _, path := range paths {
+		if status, body := doTestRequest(t, app, http.MethodGet, path, other, nil); status != http.StatusNotFound {
+			t.Errorf("GET %s by another user : status %d , want %d : %s", path, status, http.StatusNotFound, body)
+		}
+
+		if status, body := doTestRequest(t, app, http.MethodGet, path, owner, nil); status != http.StatusOK {
+			t.Errorf("GET %s by the owner : status %d , want %d : %s", path, status, http.StatusOK, body)
+		}
+	}
+
+	// the owner's receipt needs the pdf font , the ownership check runs before rendering
+	receiptPath := fmt.Sprintf("/api/orders/%d/receipt.pdf", order.ID)
+	if status, body := doTestRequest(t, app, http.MethodGet, receiptPath, other, nil); status != http.StatusNotFound {
+		t.Errorf("GET %s by another user : status %d , want %d : %s", receiptPath, status, http.StatusNotFound, body)
+	}
+}
+
+func TestConfirmedCartItemIsLocked(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	phone := createTestPhone(t, db, 5)
+	owner := createTestUser(t, db, "owner", "guess")
+	_, item, _ := createTestOrder(t, db, owner, phone)
+
+	path := fmt.Sprintf("/api/carts/items/%d", item.ID)
+
+	if status, body := doTestRequest(t, app, http.MethodPatch, path, owner, map[string]int{"amount": 2}); status != http.StatusConflict {
+		t.Errorf("update confirmed item : status %d , want %d : %s", status, http.StatusConflict, body)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodDelete, path, owner, nil); status != http.StatusConflict {
+		t.Errorf("delete confirmed item : status %d , want %d : %s", status, http.StatusConflict, body)
+	}
+}
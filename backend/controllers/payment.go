@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/middlewares"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PaymentController(app fiber.Router, configClients configs.ConfigClients) {
+	paymentController := app.Group("/payments")
+	paymentService := services.NewPaymentService(configClients)
+	userValidate := validates.NewUserValidate()
+	paymentValidate := validates.NewPaymentValidate()
+
+	paymentController.Post("", middlewares.Idempotency(configClients.Redis), paymentValidate.ValidateCreatePayment, paymentService.CreatePayment)
+	paymentController.Post("/webhook", paymentService.HandlePaymentWebhook)
+	paymentController.Post("/:id/confirm", userValidate.ValidateRoleAdmin, paymentService.ConfirmPayment)
+	paymentController.Post("/:id/refund", userValidate.ValidateRoleAdmin, paymentService.RefundPayment)
+}
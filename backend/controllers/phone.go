@@ -12,10 +12,21 @@ func PhoneController(app fiber.Router, configClients configs.ConfigClients) {
 	phoneService := services.NewPhoneService(configClients)
 	userValidate := validates.NewUserValidate()
 	phoneValidate := validates.NewPhoneValidate()
+	phoneUnitService := services.NewPhoneUnitService(configClients)
+	phoneUnitValidate := validates.NewPhoneUnitValidate()
+	warrantyService := services.NewWarrantyService(configClients)
+	warrantyValidate := validates.NewWarrantyValidate()
 
 	phoneController.Get("", phoneService.GetPhones)
 	phoneController.Get("/images/:id", phoneService.GetPhoneImageByID)
+	phoneController.Get("/low-stock", userValidate.ValidateRoleAdmin, phoneService.GetLowStockPhones)
+	phoneController.Get("/units/imei/:imei", userValidate.ValidateRoleAdmin, phoneUnitService.GetPhoneUnitByIMEI)
 	phoneController.Post("", userValidate.ValidateRoleAdmin, phoneValidate.ValidateCreatePhone, phoneService.CreatePhone)
 	phoneController.Put("/:id", userValidate.ValidateRoleAdmin, phoneValidate.ValidateUpdatePhone, phoneService.UpdatePhone)
 	phoneController.Delete("/:id", userValidate.ValidateRoleAdmin, phoneService.DeletePhone)
+	phoneController.Get("/:id/stock-movements", userValidate.ValidateRoleAdmin, phoneService.GetStockMovements)
+	phoneController.Post("/:id/stock-adjustments", userValidate.ValidateRoleAdmin, phoneValidate.ValidateAdjustStock, phoneService.AdjustStock)
+	phoneController.Get("/:id/units", userValidate.ValidateRoleAdmin, phoneUnitService.GetPhoneUnits)
+	phoneController.Post("/:id/units", userValidate.ValidateRoleAdmin, phoneUnitValidate.ValidateRegisterPhoneUnits, phoneUnitService.RegisterPhoneUnits)
+	phoneController.Put("/:id/warranty-terms", userValidate.ValidateRoleAdmin, warrantyValidate.ValidateSetWarrantyTerms, warrantyService.SetWarrantyTerms)
 }
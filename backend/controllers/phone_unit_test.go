@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+func TestRestockedPhoneUnitKeepsItsBuyer(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	const imei = "356938035643809"
+
+	admin := createTestUser(t, db, "admin", "admin")
+	owner := createTestUser(t, db, "owner", "guess")
+	phone := createTestPhone(t, db, 5)
+	order, item, _ := createTestOrder(t, db, owner, phone)
+
+	if err := db.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+		"status":          models.OrderStatusPacking,
+		"tracking_number": nil,
+	}).Error; err != nil {
+		t.Fatalf("update order : %v", err)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/phones/%d/units", phone.ID), admin, map[string]interface{}{
+		"units": []map[string]string{{"imei": imei}},
+	}); status != http.StatusCreated {
+		t.Fatalf("register phone units : status %d : %s", status, body)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/orders/%d/units", order.ID), admin, map[string]interface{}{
+		"assignments": []map[string]interface{}{{"item_id": item.ID, "imei": imei}},
+	}); status != http.StatusOK {
+		t.Fatalf("assign order units : status %d : %s", status, body)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPost, "/api/orders/add-tracking", admin, map[string]interface{}{
+		"order_id":        order.ID,
+		"tracking_number": "TH0000000001",
+	}); status != http.StatusOK {
+		t.Fatalf("add tracking number : status %d : %s", status, body)
+	}
+
+	var returnRequest models.Return
+	if err := db.Where("order_id = ?", order.ID).First(&returnRequest).Error; err != nil {
+		t.Fatalf("get return : %v", err)
+	}
+
+	if err := db.Model(&models.Return{}).Where("id = ?", returnRequest.ID).Update("status", models.ReturnStatusApproved).Error; err != nil {
+		t.Fatalf("approve return : %v", err)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/returns/%d/receive", returnRequest.ID), admin, map[string]interface{}{
+		"restock": true,
+		"imeis":   []string{imei},
+	}); status != http.StatusOK {
+		t.Fatalf("receive return : status %d : %s", status, body)
+	}
+
+	other := createTestUser(t, db, "other", "guess")
+	resold := createTestPackingOrder(t, db, other, phone)
+
+	var resoldItem models.Item
+	if err := db.Where("cart_id = ?", resold.CartID).First(&resoldItem).Error; err != nil {
+		t.Fatalf("get item : %v", err)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/orders/%d/units", resold.ID), admin, map[string]interface{}{
+		"assignments": []map[string]interface{}{{"item_id": resoldItem.ID, "imei": imei}},
+	}); status != http.StatusOK {
+		t.Fatalf("assign restocked unit : status %d : %s", status, body)
+	}
+
+	status, body := doTestRequest(t, app, http.MethodGet, "/api/phones/units/imei/"+imei, admin, nil)
+	if status != http.StatusOK {
+		t.Fatalf("get phone unit : status %d : %s", status, body)
+	}
+
+	var resp struct {
+		Data struct {
+			Unit      models.PhoneUnit                `json:"unit"`
+			Histories []models.PhoneUnitStatusHistory `json:"histories"`
+			Order     *struct {
+				ID uint `json:"id"`
+			} `json:"order"`
+			Buyer *struct {
+				Username string `json:"username"`
+			} `json:"buyer"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("unmarshal phone unit : %v", err)
+	}
+
+	if resp.Data.Unit.Status != models.PhoneUnitStatusReserved || resp.Data.Unit.OrderID == nil || *resp.Data.Unit.OrderID != resold.ID {
+		t.Errorf("unit is %s on order %v , want %s on %d", resp.Data.Unit.Status, resp.Data.Unit.OrderID, models.PhoneUnitStatusReserved, resold.ID)
+	}
+
+	if resp.Data.Order == nil || resp.Data.Order.ID != order.ID {
+		t.Errorf("unit answers order %+v , want %d", resp.Data.Order, order.ID)
+	}
+
+	if resp.Data.Buyer == nil || resp.Data.Buyer.Username != owner.Username {
+		t.Errorf("unit answers buyer %+v , want %s", resp.Data.Buyer, owner.Username)
+	}
+
+	want := []string{
+		models.PhoneUnitStatusInStock,
+		models.PhoneUnitStatusReserved,
+		models.PhoneUnitStatusSold,
+		models.PhoneUnitStatusInStock,
+		models.PhoneUnitStatusReserved,
+	}
+	if len(resp.Data.Histories) != len(want) {
+		t.Fatalf("%d histories , want %d", len(resp.Data.Histories), len(want))
+	}
+
+	for i, history := range resp.Data.Histories {
+		if history.ToStatus != want[i] {
+			t.Errorf("history %d moved to %s , want %s", i, history.ToStatus, want[i])
+		}
+	}
+
+	if sold := resp.Data.Histories[2]; sold.OrderID == nil || *sold.OrderID != order.ID || sold.ItemID == nil || *sold.ItemID != item.ID {
+		t.Errorf("sale history is on order %v item %v , want %d and %d", sold.OrderID, sold.ItemID, order.ID, item.ID)
+	}
+}
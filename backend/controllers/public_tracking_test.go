@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+func TestPublicOrderTrackingIsRateLimitedPerIP(t *testing.T) {
+	t.Setenv("PUBLIC_RATE_LIMIT", "3")
+
+	app, _ := newTestApp(t)
+
+	body := map[string]string{
+		"order_number": "KAB-2026-00000000",
+		"phone_number": "0812345678",
+	}
+
+	for i := 0; i < 3; i++ {
+		status, respBody := doTestRequest(t, app, http.MethodPost, "/api/orders/public-tracking", models.User{}, body)
+		if status != http.StatusNotFound {
+			t.Fatalf("request %d : status %d , want 404 : %s", i+1, status, respBody)
+		}
+	}
+
+	status, respBody := doTestRequest(t, app, http.MethodPost, "/api/orders/public-tracking", models.User{}, body)
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("status %d , want 429 : %s", status, respBody)
+	}
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PurchaseOrderController(app fiber.Router, configClients configs.ConfigClients) {
+	purchaseOrderController := app.Group("/purchase-orders")
+	purchaseOrderService := services.NewPurchaseOrderService(configClients)
+	userValidate := validates.NewUserValidate()
+	purchaseOrderValidate := validates.NewPurchaseOrderValidate()
+
+	purchaseOrderController.Get("", userValidate.ValidateRoleAdmin, purchaseOrderService.GetPurchaseOrders)
+	purchaseOrderController.Post("", userValidate.ValidateRoleAdmin, purchaseOrderValidate.ValidateSavePurchaseOrder, purchaseOrderService.CreatePurchaseOrder)
+	purchaseOrderController.Get("/:id", userValidate.ValidateRoleAdmin, purchaseOrderService.GetPurchaseOrderByID)
+	purchaseOrderController.Put("/:id", userValidate.ValidateRoleAdmin, purchaseOrderValidate.ValidateSavePurchaseOrder, purchaseOrderService.UpdatePurchaseOrder)
+	purchaseOrderController.Post("/:id/send", userValidate.ValidateRoleAdmin, purchaseOrderService.SendPurchaseOrder)
+	purchaseOrderController.Post("/:id/receive", userValidate.ValidateRoleAdmin, purchaseOrderValidate.ValidateReceivePurchaseOrder, purchaseOrderService.ReceivePurchaseOrder)
+	purchaseOrderController.Post("/:id/cancel", userValidate.ValidateRoleAdmin, purchaseOrderService.CancelPurchaseOrder)
+}
@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ReturnController(app fiber.Router, configClients configs.ConfigClients) {
+	returnController := app.Group("/returns")
+	returnService := services.NewReturnService(configClients)
+	userValidate := validates.NewUserValidate()
+	returnValidate := validates.NewReturnValidate()
+
+	returnController.Get("", userValidate.ValidateRoleAdmin, returnService.GetReturns)
+	returnController.Post("/:id/approve", userValidate.ValidateRoleAdmin, returnValidate.ValidateReviewReturn, returnService.ApproveReturn)
+	returnController.Post("/:id/reject", userValidate.ValidateRoleAdmin, returnValidate.ValidateReviewReturn, returnService.RejectReturn)
+	returnController.Post("/:id/receive", userValidate.ValidateRoleAdmin, returnValidate.ValidateReceiveReturn, returnService.ReceiveReturn)
+	returnController.Post("/:id/complete", userValidate.ValidateRoleAdmin, returnService.CompleteReturn)
+}
@@ -11,4 +11,12 @@ func SetUpController(app *fiber.App, configClients configs.ConfigClients) {
 	UserController(controller, configClients)
 	PhoneController(controller, configClients)
 	CartController(controller, configClients)
+	PaymentController(controller, configClients)
+	ReturnController(controller, configClients)
+	ShippingController(controller, configClients)
+	ShipmentController(controller, configClients)
+	StockTakeController(controller, configClients)
+	SupplierController(controller, configClients)
+	PurchaseOrderController(controller, configClients)
+	WarrantyController(controller, configClients)
 }
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testUserHeader = "X-Test-User-ID"
+
+// newTestApp serves every controller on a fresh sqlite file and an in memory redis.
+// Transactions take the database write lock when they begin, so parallel requests queue up
+// the way row locks make them queue on mysql. Requests name their user in testUserHeader.
+func newTestApp(t *testing.T) (*fiber.App, configs.ConfigClients) {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?_txlock=immediate&_pragma=busy_timeout(10000)&_pragma=foreign_keys(0)", filepath.Join(t.TempDir(), "test.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database : %v", err)
+	}
+
+	if err := db.AutoMigrate(
+		models.User{},
+		models.Phone{},
+		models.Cart{},
+		models.Item{},
+		models.Order{},
+		models.OrderStatusHistory{},
+		models.Payment{},
+		models.PaymentSlip{},
+		models.Return{},
+		models.TaxInvoice{},
+		models.TaxInvoiceSequence{},
+		models.ShippingCarrier{},
+		models.ShippingRate{},
+		models.ShipmentEvent{},
+		models.StockMovement{},
+		models.StockTake{},
+		models.StockTakeLine{},
+		models.LowStockAlert{},
+		models.Supplier{},
+		models.PurchaseOrder{},
+		models.PurchaseOrderLine{},
+		models.PhoneUnit{},
+		models.PhoneUnitStatusHistory{},
+		models.WarrantyTerm{},
+		models.Warranty{},
+		models.WarrantyClaim{},
+		models.WarrantyClaimStatusHistory{},
+	); err != nil {
+		t.Fatalf("migrate database : %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get database connection : %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	redisServer := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	configClients := configs.ConfigClients{
+		DB:    db,
+		Redis: redisClient,
+	}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if userID := c.Get(testUserHeader); userID != "" {
+			var user models.User
+			if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+				return c.Status(fiber.StatusUnauthorized).JSON(utils.ErrorResponse{
+					Message: "test user not found",
+					Error:   err,
+				})
+			}
+			c.Locals("user", user)
+		}
+		return c.Next()
+	})
+
+	SetUpController(app, configClients)
+
+	return app, configClients
+}
+
+// createTestUser creates a user with an empty pending cart.
+func createTestUser(t *testing.T, db *gorm.DB, username string, role string) models.User {
+	t.Helper()
+
+	user := models.User{
+		Username:    username,
+		FirstName:   username,
+		LastName:    "test",
+		PhoneNumber: "0812345678",
+		Password:    "password",
+		Role:        role,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user : %v", err)
+	}
+
+	if err := db.Create(&models.Cart{UserId: user.ID, Status: "PENDING"}).Error; err != nil {
+		t.Fatalf("create cart : %v", err)
+	}
+
+	return user
+}
+
+// createTestPhone creates a phone with amount in stock, recorded as an opening receipt in the ledger.
+func createTestPhone(t *testing.T, db *gorm.DB, amount int) models.Phone {
+	t.Helper()
+
+	phone := models.Phone{
+		Price:     models.NewMoneyFromBaht(10000),
+		BrandName: "Test",
+		ModelName: "Test Phone",
+		OS:        "android",
+		Amount:    amount,
+		Weight:    200,
+	}
+	if err := db.Create(&phone).Error; err != nil {
+		t.Fatalf("create phone : %v", err)
+	}
+
+	if err := db.Create(&models.StockMovement{
+		PhoneID:      phone.ID,
+		Type:         models.StockMovementReceipt,
+		Quantity:     amount,
+		BalanceAfter: amount,
+		Note:         "opening stock",
+	}).Error; err != nil {
+		t.Fatalf("create stock movement : %v", err)
+	}
+
+	return phone
+}
+
+// createTestCarrier creates an active carrier that ships anywhere for a flat fee.
+func createTestCarrier(t *testing.T, db *gorm.DB, code string) models.ShippingCarrier {
+	t.Helper()
+
+	carrier := models.ShippingCarrier{
+		Code:   code,
+		Name:   code,
+		Active: true,
+		Rates: []models.ShippingRate{
+			{Zone: models.ShippingZoneBangkok, MaxWeight: 100000, Fee: models.NewMoneyFromBaht(50)},
+			{Zone: models.ShippingZoneProvince, MaxWeight: 100000, Fee: models.NewMoneyFromBaht(80)},
+		},
+	}
+	if err := db.Create(&carrier).Error; err != nil {
+		t.Fatalf("create carrier : %v", err)
+	}
+
+	return carrier
+}
+
+// addTestCartItem puts amount of the phone in the user's pending cart.
+func addTestCartItem(t *testing.T, db *gorm.DB, user models.User, phone models.Phone, amount int) models.Item {
+	t.Helper()
+
+	var cart models.Cart
+	if err := db.Where("user_id = ? AND status = ?", user.ID, "PENDING").First(&cart).Error; err != nil {
+		t.Fatalf("get cart : %v", err)
+	}
+
+	item := models.Item{
+		CartID:  cart.ID,
+		PhoneID: phone.ID,
+		Amount:  amount,
+	}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatalf("create item : %v", err)
+	}
+
+	return item
+}
+
+// doTestRequest sends body as json on behalf of user, a zero user sends no user at all.
+func doTestRequest(t *testing.T, app *fiber.App, method string, path string, user models.User, body interface{}) (int, []byte) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		rawBody, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body : %v", err)
+		}
+		reader = bytes.NewReader(rawBody)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if user.ID != 0 {
+		req.Header.Set(testUserHeader, fmt.Sprint(user.ID))
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s : %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response : %v", err)
+	}
+
+	return resp.StatusCode, respBody
+}
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ShipmentController(app fiber.Router, configClients configs.ConfigClients) {
+	shipmentController := app.Group("/shipments")
+	shipmentService := services.NewShipmentService(configClients)
+
+	shipmentController.Post("/webhook", shipmentService.HandleShipmentWebhook)
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+const testCarrierSecret = "carrier-secret"
+
+// newShipmentTestApp serves the app with the fake carrier registered and one order shipped on it.
+func newShipmentTestApp(t *testing.T) (*fiber.App, *gorm.DB, models.Order) {
+	t.Helper()
+
+	t.Setenv("APP_ENV", "test")
+	t.Setenv("CARRIER_WEBHOOK_SECRET", testCarrierSecret)
+
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	user := createTestUser(t, db, "buyer", "guess")
+	phone := createTestPhone(t, db, 1)
+	item := addTestCartItem(t, db, user, phone, 1)
+
+	order := models.Order{
+		CartID:          item.CartID,
+		OrderNumber:     "KAB-2026-000001",
+		TrackingNumber:  "FAKE000001",
+		ShippingCarrier: "FAKE",
+		Status:          models.OrderStatusShipped,
+	}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("create order : %v", err)
+	}
+
+	return app, db, order
+}
+
+func signTestWebhook(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testCarrierSecret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func postTestShipmentWebhook(t *testing.T, app *fiber.App, body []byte, signature string) (int, []byte) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shipments/webhook?carrier=FAKE", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Carrier-Signature", signature)
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("post shipment webhook : %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response : %v", err)
+	}
+
+	return resp.StatusCode, respBody
+}
+
+func testShipmentWebhookBody(t *testing.T, trackingNumber string, status string, occurredAt time.Time) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"events": []map[string]interface{}{{
+			"tracking_number": trackingNumber,
+			"status":          status,
+			"description":     "scan",
+			"location":        "Bangkok",
+			"occurred_at":     occurredAt,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("marshal webhook body : %v", err)
+	}
+
+	return body
+}
+
+func countTestShipmentEvents(t *testing.T, db *gorm.DB, orderID uint) int64 {
+	t.Helper()
+
+	var count int64
+	if err := db.Model(&models.ShipmentEvent{}).Where("order_id = ?", orderID).Count(&count).Error; err != nil {
+		t.Fatalf("count shipment events : %v", err)
+	}
+
+	return count
+}
+
+func TestShipmentWebhookValidSignature(t *testing.T) {
+	app, db, order := newShipmentTestApp(t)
+
+	body := testShipmentWebhookBody(t, order.TrackingNumber, models.ShipmentStatusInTransit, time.Now())
+	status, respBody := postTestShipmentWebhook(t, app, body, signTestWebhook(body))
+	if status != http.StatusOK {
+		t.Fatalf("status %d , want 200 : %s", status, respBody)
+	}
+
+	if count := countTestShipmentEvents(t, db, order.ID); count != 1 {
+		t.Errorf("%d shipment events recorded , want 1", count)
+	}
+}
+
+func TestShipmentWebhookBadSignature(t *testing.T) {
+	app, db, order := newShipmentTestApp(t)
+
+	body := testShipmentWebhookBody(t, order.TrackingNumber, models.ShipmentStatusDelivered, time.Now())
+	status, respBody := postTestShipmentWebhook(t, app, body, signTestWebhook([]byte("another body")))
+	if status != http.StatusUnauthorized {
+		t.Fatalf("status %d , want 401 : %s", status, respBody)
+	}
+
+	if count := countTestShipmentEvents(t, db, order.ID); count != 0 {
+		t.Errorf("%d shipment events recorded , want 0", count)
+	}
+
+	var current models.Order
+	if err := db.Where("id = ?", order.ID).First(&current).Error; err != nil {
+		t.Fatalf("get order : %v", err)
+	}
+
+	if current.Status != models.OrderStatusShipped {
+		t.Errorf("order status %s , want %s", current.Status, models.OrderStatusShipped)
+	}
+}
+
+func TestShipmentWebhookDuplicateEvent(t *testing.T) {
+	app, db, order := newShipmentTestApp(t)
+
+	body := testShipmentWebhookBody(t, order.TrackingNumber, models.ShipmentStatusInTransit, time.Now())
+	for i := 0; i < 2; i++ {
+		status, respBody := postTestShipmentWebhook(t, app, body, signTestWebhook(body))
+		if status != http.StatusOK {
+			t.Fatalf("delivery %d : status %d , want 200 : %s", i+1, status, respBody)
+		}
+	}
+
+	if count := countTestShipmentEvents(t, db, order.ID); count != 1 {
+		t.Errorf("%d shipment events recorded , want 1", count)
+	}
+}
+
+func TestShipmentWebhookDeliversShippedOrder(t *testing.T) {
+	app, db, order := newShipmentTestApp(t)
+
+	body := testShipmentWebhookBody(t, order.TrackingNumber, models.ShipmentStatusDelivered, time.Now())
+	status, respBody := postTestShipmentWebhook(t, app, body, signTestWebhook(body))
+	if status != http.StatusOK {
+		t.Fatalf("status %d , want 200 : %s", status, respBody)
+	}
+
+	var current models.Order
+	if err := db.Where("id = ?", order.ID).First(&current).Error; err != nil {
+		t.Fatalf("get order : %v", err)
+	}
+
+	if current.Status != models.OrderStatusDelivered {
+		t.Errorf("order status %s , want %s", current.Status, models.OrderStatusDelivered)
+	}
+
+	var history models.OrderStatusHistory
+	if err := db.Where("order_id = ? AND to_status = ?", order.ID, models.OrderStatusDelivered).First(&history).Error; err != nil {
+		t.Errorf("delivered status history : %v", err)
+	} else if history.FromStatus != models.OrderStatusShipped || history.ChangedByID != nil {
+		t.Errorf("history from %s by %v , want from %s by the system", history.FromStatus, history.ChangedByID, models.OrderStatusShipped)
+	}
+}
+
+func TestShipmentWebhookFakeCarrierOnlyInDevelopment(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("CARRIER_WEBHOOK_SECRET", testCarrierSecret)
+
+	app, _ := newTestApp(t)
+
+	body := testShipmentWebhookBody(t, "FAKE000001", models.ShipmentStatusDelivered, time.Now())
+	status, respBody := postTestShipmentWebhook(t, app, body, signTestWebhook(body))
+	if status != http.StatusBadRequest {
+		t.Fatalf("status %d , want 400 : %s", status, respBody)
+	}
+}
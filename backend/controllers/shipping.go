@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ShippingController(app fiber.Router, configClients configs.ConfigClients) {
+	shippingController := app.Group("/shippings")
+	shippingService := services.NewShippingService(configClients)
+	userValidate := validates.NewUserValidate()
+	shippingValidate := validates.NewShippingValidate()
+
+	shippingController.Get("/carriers", userValidate.ValidateRoleAdmin, shippingService.GetShippingCarriers)
+	shippingController.Post("/carriers", userValidate.ValidateRoleAdmin, shippingValidate.ValidateCreateShippingCarrier, shippingService.CreateShippingCarrier)
+	shippingController.Patch("/carriers/:id", userValidate.ValidateRoleAdmin, shippingValidate.ValidateUpdateShippingCarrier, shippingService.UpdateShippingCarrier)
+	shippingController.Post("/carriers/:id/rates", userValidate.ValidateRoleAdmin, shippingValidate.ValidateShippingRate, shippingService.CreateShippingRate)
+	shippingController.Patch("/rates/:id", userValidate.ValidateRoleAdmin, shippingValidate.ValidateShippingRate, shippingService.UpdateShippingRate)
+	shippingController.Delete("/rates/:id", userValidate.ValidateRoleAdmin, shippingService.DeleteShippingRate)
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+)
+
+func StockTakeController(app fiber.Router, configClients configs.ConfigClients) {
+	stockTakeController := app.Group("/stock-takes")
+	stockTakeService := services.NewStockTakeService(configClients)
+	userValidate := validates.NewUserValidate()
+	stockTakeValidate := validates.NewStockTakeValidate()
+
+	stockTakeController.Post("", userValidate.ValidateRoleAdmin, stockTakeValidate.ValidateOpenStockTake, stockTakeService.OpenStockTake)
+	stockTakeController.Get("", userValidate.ValidateRoleAdmin, stockTakeService.GetStockTakes)
+	stockTakeController.Get("/:id", userValidate.ValidateRoleAdmin, stockTakeService.GetStockTakeByID)
+	stockTakeController.Put("/:id/counts", userValidate.ValidateRoleAdmin, stockTakeValidate.ValidateSubmitStockTakeCounts, stockTakeService.SubmitStockTakeCounts)
+	stockTakeController.Post("/:id/scans", userValidate.ValidateRoleAdmin, stockTakeValidate.ValidateScanStockTake, stockTakeService.ScanStockTake)
+	stockTakeController.Post("/:id/post", userValidate.ValidateRoleAdmin, stockTakeValidate.ValidatePostStockTake, stockTakeService.PostStockTake)
+	stockTakeController.Post("/:id/cancel", userValidate.ValidateRoleAdmin, stockTakeService.CancelStockTake)
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+// openTestStockTake opens a stock take and submits the counted amount of the phone.
+func openTestStockTake(t *testing.T, app *fiber.App, admin models.User, phone models.Phone, counted int) uint {
+	t.Helper()
+
+	status, body := doTestRequest(t, app, http.MethodPost, "/api/stock-takes", admin, map[string]string{"note": "shelf a"})
+	if status != http.StatusCreated {
+		t.Fatalf("open stock take : status %d : %s", status, body)
+	}
+
+	var resp struct {
+		Data models.StockTake `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("unmarshal stock take : %v", err)
+	}
+
+	status, body = doTestRequest(t, app, http.MethodPut, fmt.Sprintf("/api/stock-takes/%d/counts", resp.Data.ID), admin, map[string]interface{}{
+		"counts": []map[string]interface{}{
+			{"phone_id": phone.ID, "counted_amount": counted},
+		},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("submit stock take counts : status %d : %s", status, body)
+	}
+
+	return resp.Data.ID
+}
+
+// adjustTestStock moves the phone's stock the way a sale or receipt would while the count is open.
+func adjustTestStock(t *testing.T, app *fiber.App, admin models.User, phone models.Phone, quantity int) {
+	t.Helper()
+
+	status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/phones/%d/stock-adjustments", phone.ID), admin, map[string]interface{}{
+		"quantity": quantity,
+		"reason":   "sold during the count",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("adjust stock : status %d : %s", status, body)
+	}
+}
+
+func getTestPhoneAmount(t *testing.T, db *gorm.DB, phone models.Phone) int {
+	t.Helper()
+
+	var amount int
+	if err := db.Model(&models.Phone{}).Where("id = ?", phone.ID).Pluck("amount", &amount).Error; err != nil {
+		t.Fatalf("get phone amount : %v", err)
+	}
+
+	return amount
+}
+
+func TestPostStockTakeKeepsSalesAfterTheCount(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	admin := createTestUser(t, db, "admin", "admin")
+	phone := createTestPhone(t, db, 10)
+
+	// 8 on the shelf against 10 in the system , 2 are missing
+	stockTakeID := openTestStockTake(t, app, admin, phone, 8)
+	adjustTestStock(t, app, admin, phone, -3)
+
+	status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/stock-takes/%d/post", stockTakeID), admin, map[string]string{"reason": "monthly count"})
+	if status != http.StatusOK {
+		t.Fatalf("post stock take : status %d : %s", status, body)
+	}
+
+	if amount := getTestPhoneAmount(t, db, phone); amount != 5 {
+		t.Errorf("phone amount is %d , want 5", amount)
+	}
+
+	var line models.StockTakeLine
+	if err := db.Where("stock_take_id = ? AND phone_id = ?", stockTakeID, phone.ID).First(&line).Error; err != nil {
+		t.Fatalf("get stock take line : %v", err)
+	}
+
+	if line.ExpectedAmount != 10 || line.Variance != -2 {
+		t.Errorf("line expected %d variance %d , want 10 and -2", line.ExpectedAmount, line.Variance)
+	}
+
+	var movement models.StockMovement
+	if err := db.Where("phone_id = ? AND type = ?", phone.ID, models.StockMovementStockTake).First(&movement).Error; err != nil {
+		t.Fatalf("get stock take movement : %v", err)
+	}
+
+	if movement.Quantity != -2 || movement.BalanceAfter != 5 {
+		t.Errorf("movement quantity %d balance %d , want -2 and 5", movement.Quantity, movement.BalanceAfter)
+	}
+}
+
+func TestPostStockTakeRefusesToGoBelowZero(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	admin := createTestUser(t, db, "admin", "admin")
+	phone := createTestPhone(t, db, 3)
+
+	stockTakeID := openTestStockTake(t, app, admin, phone, 1)
+	adjustTestStock(t, app, admin, phone, -3)
+
+	status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/stock-takes/%d/post", stockTakeID), admin, map[string]string{"reason": "monthly count"})
+	if status != http.StatusConflict {
+		t.Errorf("post stock take : status %d , want %d : %s", status, http.StatusConflict, body)
+	}
+
+	if amount := getTestPhoneAmount(t, db, phone); amount != 0 {
+		t.Errorf("phone amount is %d , want 0", amount)
+	}
+
+	var stockTake models.StockTake
+	if err := db.Where("id = ?", stockTakeID).First(&stockTake).Error; err != nil {
+		t.Fatalf("get stock take : %v", err)
+	}
+
+	if stockTake.Status != models.StockTakeStatusOpen {
+		t.Errorf("stock take is %s , want %s", stockTake.Status, models.StockTakeStatusOpen)
+	}
+}
+
+func TestStockTakeKeepsTheExpectedAmountOfTheFirstCount(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	admin := createTestUser(t, db, "admin", "admin")
+	phone := createTestPhone(t, db, 10)
+	const barcode = "8850000000001"
+	if err := db.Model(&models.Phone{}).Where("id = ?", phone.ID).Update("barcode", barcode).Error; err != nil {
+		t.Fatalf("update barcode : %v", err)
+	}
+
+	stockTakeID := openTestStockTake(t, app, admin, phone, 8)
+	adjustTestStock(t, app, admin, phone, -3)
+
+	path := fmt.Sprintf("/api/stock-takes/%d", stockTakeID)
+	if status, body := doTestRequest(t, app, http.MethodPut, path+"/counts", admin, map[string]interface{}{
+		"counts": []map[string]interface{}{
+			{"phone_id": phone.ID, "counted_amount": 7},
+		},
+	}); status != http.StatusOK {
+		t.Fatalf("resubmit stock take counts : status %d : %s", status, body)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPost, path+"/scans", admin, map[string]interface{}{
+		"barcode": barcode,
+	}); status != http.StatusOK {
+		t.Fatalf("scan stock take : status %d : %s", status, body)
+	}
+
+	var line models.StockTakeLine
+	if err := db.Where("stock_take_id = ? AND phone_id = ?", stockTakeID, phone.ID).First(&line).Error; err != nil {
+		t.Fatalf("get stock take line : %v", err)
+	}
+
+	if line.CountedAmount != 8 || line.ExpectedAmount != 10 || line.Variance != -2 {
+		t.Errorf("line counted %d expected %d variance %d , want 8 , 10 and -2", line.CountedAmount, line.ExpectedAmount, line.Variance)
+	}
+}
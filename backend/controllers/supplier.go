@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SupplierController(app fiber.Router, configClients configs.ConfigClients) {
+	supplierController := app.Group("/suppliers")
+	supplierService := services.NewSupplierService(configClients)
+	userValidate := validates.NewUserValidate()
+	supplierValidate := validates.NewSupplierValidate()
+
+	supplierController.Get("", userValidate.ValidateRoleAdmin, supplierService.GetSuppliers)
+	supplierController.Post("", userValidate.ValidateRoleAdmin, supplierValidate.ValidateCreateSupplier, supplierService.CreateSupplier)
+	supplierController.Patch("/:id", userValidate.ValidateRoleAdmin, supplierValidate.ValidateUpdateSupplier, supplierService.UpdateSupplier)
+	supplierController.Delete("/:id", userValidate.ValidateRoleAdmin, supplierService.DeleteSupplier)
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+func createTestPackingOrder(t *testing.T, db *gorm.DB, user models.User, phone models.Phone) models.Order {
+	t.Helper()
+
+	order, _, _ := createTestOrder(t, db, user, phone)
+	if err := db.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+		"status":          models.OrderStatusPacking,
+		"tracking_number": nil,
+	}).Error; err != nil {
+		t.Fatalf("update order : %v", err)
+	}
+
+	return order
+}
+
+func importTestTrackingNumbers(t *testing.T, app *fiber.App, admin models.User, csv string) []services.TrackingImportResult {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "tracking.csv")
+	if err != nil {
+		t.Fatalf("create form file : %v", err)
+	}
+	part.Write([]byte(csv))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/orders/tracking/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set(testUserHeader, fmt.Sprint(admin.ID))
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("import tracking numbers : %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response : %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("import tracking numbers : status %d : %s", resp.StatusCode, respBody)
+	}
+
+	var result struct {
+		Data struct {
+			Results []services.TrackingImportResult `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		t.Fatalf("unmarshal import result : %v", err)
+	}
+
+	return result.Data.Results
+}
+
+func TestImportTrackingNumbersRetriesAFailedOrderAndIgnoresCase(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	admin := createTestUser(t, db, "admin", "admin")
+	buyer := createTestUser(t, db, "buyer", "guess")
+	phone := createTestPhone(t, db, 5)
+
+	shipped, _, _ := createTestOrder(t, db, buyer, phone)
+	first := createTestPackingOrder(t, db, buyer, phone)
+	second := createTestPackingOrder(t, db, buyer, phone)
+
+	csv := fmt.Sprintf("order_id,tracking_number\n%d,%s\n%d,TH0000000002\n%d,th0000000002\n",
+		first.ID, shipped.TrackingNumber,
+		first.ID,
+		second.ID,
+	)
+
+	results := importTestTrackingNumbers(t, app, admin, csv)
+	if len(results) != 3 {
+		t.Fatalf("%d results , want 3 : %+v", len(results), results)
+	}
+
+	want := []string{services.TrackingImportFailed, services.TrackingImportApplied, services.TrackingImportFailed}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("row %d is %s (%s) , want %s", result.Row, result.Status, result.Error, want[i])
+		}
+	}
+
+	if results[2].Error != "tracking number already listed on row 3" {
+		t.Errorf("row %d error is %q", results[2].Row, results[2].Error)
+	}
+
+	var order models.Order
+	if err := db.Where("id = ?", first.ID).First(&order).Error; err != nil {
+		t.Fatalf("get order : %v", err)
+	}
+
+	if order.Status != models.OrderStatusShipped || order.TrackingNumber != "TH0000000002" {
+		t.Errorf("order is %s with %s , want %s with TH0000000002", order.Status, order.TrackingNumber, models.OrderStatusShipped)
+	}
+}
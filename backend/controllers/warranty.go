@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/services"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+)
+
+func WarrantyController(app fiber.Router, configClients configs.ConfigClients) {
+	warrantyController := app.Group("/warranties")
+	warrantyService := services.NewWarrantyService(configClients)
+	userValidate := validates.NewUserValidate()
+	warrantyValidate := validates.NewWarrantyValidate()
+
+	warrantyController.Get("", warrantyService.GetWarranties)
+	warrantyController.Get("/claims", warrantyService.GetWarrantyClaims)
+	warrantyController.Get("/claims/:id", warrantyService.GetWarrantyClaimByID)
+	warrantyController.Patch("/claims/:id/status", userValidate.ValidateRoleAdmin, warrantyValidate.ValidateUpdateWarrantyClaimStatus, warrantyService.UpdateWarrantyClaimStatus)
+	warrantyController.Post("/:id/claims", warrantyValidate.ValidateCreateWarrantyClaim, warrantyService.CreateWarrantyClaim)
+}
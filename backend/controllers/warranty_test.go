@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+// deliverTestOrderWithWarranty sells the phone with a 12 month shop warranty to the user and delivers the order.
+func deliverTestOrderWithWarranty(t *testing.T, app *fiber.App, db *gorm.DB, admin models.User, user models.User) (models.Order, models.Warranty) {
+	t.Helper()
+
+	phone := createTestPhone(t, db, 5)
+	if status, body := doTestRequest(t, app, http.MethodPut, fmt.Sprintf("/api/phones/%d/warranty-terms", phone.ID), admin, map[string]interface{}{
+		"terms": []map[string]interface{}{{"type": models.WarrantyTypeShop, "months": 12}},
+	}); status != http.StatusOK {
+		t.Fatalf("set warranty terms : status %d : %s", status, body)
+	}
+
+	order, item, _ := createTestOrder(t, db, user, phone)
+	if status, body := doTestRequest(t, app, http.MethodPatch, fmt.Sprintf("/api/orders/%d/status", order.ID), admin, map[string]string{
+		"status": models.OrderStatusDelivered,
+	}); status != http.StatusOK {
+		t.Fatalf("deliver order : status %d : %s", status, body)
+	}
+
+	warranties := getTestWarranties(t, app, user, "")
+	if len(warranties) != 1 {
+		t.Fatalf("%d warranties after delivery , want 1", len(warranties))
+	}
+
+	warranty := warranties[0]
+	if warranty.OrderID != order.ID || warranty.ItemID != item.ID || warranty.Type != models.WarrantyTypeShop || warranty.Quantity != 1 || warranty.Months != 12 {
+		t.Fatalf("warranty is %+v , want one shop warranty of 12 months on order %d item %d", warranty, order.ID, item.ID)
+	}
+
+	return order, warranty
+}
+
+func getTestWarranties(t *testing.T, app *fiber.App, user models.User, query string) []models.Warranty {
+	t.Helper()
+
+	status, body := doTestRequest(t, app, http.MethodGet, "/api/warranties"+query, user, nil)
+	if status != http.StatusOK {
+		t.Fatalf("get warranties : status %d : %s", status, body)
+	}
+
+	var resp struct {
+		Data []models.Warranty `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("unmarshal warranties : %v", err)
+	}
+
+	return resp.Data
+}
+
+func TestDeliveredOrderWarrantyEndsWhenTheLineIsRefunded(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	admin := createTestUser(t, db, "admin", "admin")
+	buyer := createTestUser(t, db, "buyer", "guess")
+	order, warranty := deliverTestOrderWithWarranty(t, app, db, admin, buyer)
+
+	if !warranty.ExpiresAt.Equal(warranty.StartsAt.AddDate(0, 12, 0)) {
+		t.Errorf("warranty runs from %s to %s , want 12 months", warranty.StartsAt, warranty.ExpiresAt)
+	}
+
+	var returnRequest models.Return
+	if err := db.Where("order_id = ?", order.ID).First(&returnRequest).Error; err != nil {
+		t.Fatalf("get return : %v", err)
+	}
+
+	for _, action := range []string{"approve", "receive", "complete"} {
+		if status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/returns/%d/%s", returnRequest.ID, action), admin, map[string]interface{}{}); status != http.StatusOK {
+			t.Fatalf("%s return : status %d : %s", action, status, body)
+		}
+	}
+
+	if warranties := getTestWarranties(t, app, buyer, ""); len(warranties) != 0 {
+		t.Errorf("%d warranties still running after the refund , want 0", len(warranties))
+	}
+
+	warranties := getTestWarranties(t, app, buyer, "?include_expired=true")
+	if len(warranties) != 1 || warranties[0].Quantity != 0 {
+		t.Fatalf("warranties after the refund are %+v , want one covering nothing", warranties)
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/warranties/%d/claims", warranty.ID), buyer, map[string]string{
+		"problem": "screen does not turn on",
+	}); status != http.StatusConflict {
+		t.Errorf("claim on a refunded line : status %d , want %d : %s", status, http.StatusConflict, body)
+	}
+}
+
+func TestWarrantyClaimStatusFollowsTheTransitions(t *testing.T) {
+	app, configClients := newTestApp(t)
+	db := configClients.DB
+
+	admin := createTestUser(t, db, "admin", "admin")
+	buyer := createTestUser(t, db, "buyer", "guess")
+	_, warranty := deliverTestOrderWithWarranty(t, app, db, admin, buyer)
+
+	status, body := doTestRequest(t, app, http.MethodPost, fmt.Sprintf("/api/warranties/%d/claims", warranty.ID), buyer, map[string]string{
+		"problem": "screen does not turn on",
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("create warranty claim : status %d : %s", status, body)
+	}
+
+	var created struct {
+		Data models.WarrantyClaim `json:"data"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("unmarshal warranty claim : %v", err)
+	}
+
+	if created.Data.Status != models.WarrantyClaimStatusSubmitted {
+		t.Errorf("new claim is %s , want %s", created.Data.Status, models.WarrantyClaimStatusSubmitted)
+	}
+
+	path := fmt.Sprintf("/api/warranties/claims/%d/status", created.Data.ID)
+
+	steps := []struct {
+		status string
+		want   int
+	}{
+		{models.WarrantyClaimStatusAtServiceCenter, http.StatusConflict},
+		{models.WarrantyClaimStatusReceived, http.StatusOK},
+		{models.WarrantyClaimStatusAtServiceCenter, http.StatusOK},
+		{models.WarrantyClaimStatusRejected, http.StatusConflict},
+		{models.WarrantyClaimStatusReturned, http.StatusOK},
+		{models.WarrantyClaimStatusReceived, http.StatusConflict},
+	}
+
+	for _, step := range steps {
+		if status, body := doTestRequest(t, app, http.MethodPatch, path, admin, map[string]string{
+			"status":         step.status,
+			"service_center": "Bangkok",
+		}); status != step.want {
+			t.Errorf("move claim to %s : status %d , want %d : %s", step.status, status, step.want, body)
+		}
+	}
+
+	if status, body := doTestRequest(t, app, http.MethodPatch, path, buyer, map[string]string{
+		"status": models.WarrantyClaimStatusRejected,
+	}); status != http.StatusForbidden {
+		t.Errorf("buyer moves claim : status %d , want %d : %s", status, http.StatusForbidden, body)
+	}
+
+	status, body = doTestRequest(t, app, http.MethodGet, fmt.Sprintf("/api/warranties/claims/%d", created.Data.ID), buyer, nil)
+	if status != http.StatusOK {
+		t.Fatalf("get warranty claim : status %d : %s", status, body)
+	}
+
+	var claim struct {
+		Data models.WarrantyClaim `json:"data"`
+	}
+	if err := json.Unmarshal(body, &claim); err != nil {
+		t.Fatalf("unmarshal warranty claim : %v", err)
+	}
+
+	want := []string{
+		models.WarrantyClaimStatusSubmitted,
+		models.WarrantyClaimStatusReceived,
+		models.WarrantyClaimStatusAtServiceCenter,
+		models.WarrantyClaimStatusReturned,
+	}
+	if claim.Data.Status != models.WarrantyClaimStatusReturned || len(claim.Data.StatusHistories) != len(want) {
+		t.Fatalf("claim is %s with %d histories , want %s with %d", claim.Data.Status, len(claim.Data.StatusHistories), models.WarrantyClaimStatusReturned, len(want))
+	}
+
+	for i, history := range claim.Data.StatusHistories {
+		if history.ToStatus != want[i] {
+			t.Errorf("history %d moved to %s , want %s", i, history.ToStatus, want[i])
+		}
+	}
+}
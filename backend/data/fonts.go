@@ -0,0 +1,8 @@
+package data
+
+import "embed"
+
+// Fonts holds the faces pdf documents are rendered with, they are built into the binary so rendering works offline.
+//
+//go:embed fonts
+var Fonts embed.FS
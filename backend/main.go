@@ -10,6 +10,7 @@ import (
 	"github.com/BaimhonS/kab-phone/controllers"
 	"github.com/BaimhonS/kab-phone/middlewares"
 	"github.com/BaimhonS/kab-phone/scripts"
+	"github.com/BaimhonS/kab-phone/services"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -27,6 +28,8 @@ func main() {
 
 	configClients := configs.SetUpConfigs()
 
+	services.StartLowStockChecker(configClients)
+
 	app := fiber.New()
 
 	app.Use(
@@ -51,6 +54,10 @@ func handleArgument() {
 			scripts.MigrateDown(arg)
 			isHandled = true
 		}
+		if strings.Contains(arg, "reconcile-stock") {
+			scripts.ReconcileStock()
+			isHandled = true
+		}
 	}
 
 	if isHandled {
@@ -17,6 +17,9 @@ var bypassPaths = map[string][]string{
 	"POST": {
 		"/api/users/login",
 		"/api/users/register",
+		"/api/payments/webhook",
+		"/api/shipments/webhook",
+		"/api/orders/public-tracking",
 	},
 	"GET": {
 		"/api/phones",
@@ -0,0 +1,154 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyTTL = 24 * time.Hour
+
+type idempotencyRecord struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	BodyHash    string `json:"body_hash"`
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Idempotency replays the first response stored for the caller's Idempotency-Key, so a retried request
+// does not run the handler twice. A key belongs to one method and url , e.g. the item id is part of it,
+// reusing it elsewhere is refused. Requests without the header pass through.
+func Idempotency(redisClient *redis.Client) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := c.Get("Idempotency-Key")
+		if idempotencyKey == "" {
+			return c.Next()
+		}
+
+		if len(idempotencyKey) > 255 {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "idempotency key too long",
+				Error:   nil,
+			})
+		}
+
+		user, ok := c.Locals("user").(models.User)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(utils.ErrorResponse{
+				Message: "user not found",
+				Error:   nil,
+			})
+		}
+
+		bodyHash := sha256.Sum256(c.Body())
+		record := idempotencyRecord{
+			Method:   c.Method(),
+			Path:     c.Path(),
+			BodyHash: hex.EncodeToString(bodyHash[:]),
+		}
+
+		redisKey := fmt.Sprintf("idempotency:%v:%s", user.ID, idempotencyKey)
+
+		rawRecord, err := json.Marshal(record)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "marshal idempotency record error",
+				Error:   err,
+			})
+		}
+
+		isFirst, err := redisClient.SetNX(c.Context(), redisKey, rawRecord, idempotencyTTL).Result()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "set idempotency key to redis error",
+				Error:   err,
+			})
+		}
+
+		if !isFirst {
+			return replayIdempotentResponse(c, redisClient, redisKey, record)
+		}
+
+		if err := c.Next(); err != nil {
+			redisClient.Del(c.Context(), redisKey)
+			return err
+		}
+
+		// server errors are not stored so the client can retry them
+		if c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			redisClient.Del(c.Context(), redisKey)
+			return nil
+		}
+
+		record.Completed = true
+		record.StatusCode = c.Response().StatusCode()
+		record.ContentType = string(c.Response().Header.ContentType())
+		record.Body = append([]byte(nil), c.Response().Body()...)
+
+		rawRecord, err = json.Marshal(record)
+		if err != nil {
+			redisClient.Del(c.Context(), redisKey)
+			return nil
+		}
+
+		if err := redisClient.Set(c.Context(), redisKey, rawRecord, idempotencyTTL).Err(); err != nil {
+			redisClient.Del(c.Context(), redisKey)
+		}
+
+		return nil
+	}
+}
+
+func replayIdempotentResponse(c *fiber.Ctx, redisClient *redis.Client, redisKey string, request idempotencyRecord) error {
+	rawRecord, err := redisClient.Get(c.Context(), redisKey).Bytes()
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "get idempotency record from redis error, please retry",
+			Error:   err,
+		})
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(rawRecord, &record); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "unmarshal idempotency record error",
+			Error:   err,
+		})
+	}
+
+	if record.Method != request.Method || record.Path != request.Path {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("idempotency key already used on %s %s", record.Method, record.Path),
+			Error:   nil,
+		})
+	}
+
+	if record.BodyHash != request.BodyHash {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(utils.ErrorResponse{
+			Message: "idempotency key already used with a different request body",
+			Error:   nil,
+		})
+	}
+
+	if !record.Completed {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "request with this idempotency key is still processing",
+			Error:   nil,
+		})
+	}
+
+	c.Set("Idempotent-Replayed", "true")
+	c.Set("Content-Type", record.ContentType)
+
+	return c.Status(record.StatusCode).Send(record.Body)
+}
@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"errors"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+// ErrResourceLocked is returned by a loader when the caller owns the resource but may no longer change it.
+var ErrResourceLocked = errors.New("resource can no longer be changed")
+
+// OwnedResourceLoader loads the resource named by id only if user owns it,
+// it returns gorm.ErrRecordNotFound for ids of other users.
+type OwnedResourceLoader func(db *gorm.DB, user models.User, id string) (interface{}, error)
+
+// Ownership loads the resource in the :id param through load and stores it in Locals under localKey.
+// Resources of other users answer 404 like missing ones so their ids are not confirmed.
+func Ownership(db *gorm.DB, localKey string, load OwnedResourceLoader) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(models.User)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "local user not found",
+			})
+		}
+
+		resource, err := load(db, user, c.Params("id"))
+		if err != nil {
+			switch err {
+			case gorm.ErrRecordNotFound:
+				return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+					Message: localKey + " not found",
+					Error:   nil,
+				})
+			case ErrResourceLocked:
+				return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+					Message: localKey + " can no longer be changed",
+					Error:   err,
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database error",
+				Error:   err,
+			})
+		}
+
+		c.Locals(localKey, resource)
+		return c.Next()
+	}
+}
+
+// LoadPendingCartItem loads an item of the caller's cart, items of confirmed carts belong to an order and are locked.
+func LoadPendingCartItem(db *gorm.DB, user models.User, id string) (interface{}, error) {
+	var item models.Item
+	if err := db.Model(&models.Item{}).
+		Joins("JOIN carts ON carts.id = items.cart_id").
+		Where("items.id = ? AND carts.user_id = ?", id, user.ID).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	var cart models.Cart
+	if err := db.Model(&models.Cart{}).Select("id", "status").Where("id = ?", item.CartID).First(&cart).Error; err != nil {
+		return nil, err
+	}
+
+	if cart.Status != "PENDING" {
+		return nil, ErrResourceLocked
+	}
+
+	return item, nil
+}
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultPublicRateLimit = 10
+
+// PublicRateLimit allows PUBLIC_RATE_LIMIT requests a minute from one IP to an unauthenticated
+// lookup named name, so order numbers and phone numbers cannot be guessed by brute force.
+// Counts are kept in redis so every instance of the api shares them.
+func PublicRateLimit(redisClient *redis.Client, name string) func(c *fiber.Ctx) error {
+	max, err := strconv.Atoi(os.Getenv("PUBLIC_RATE_LIMIT"))
+	if err != nil || max <= 0 {
+		max = defaultPublicRateLimit
+	}
+
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return fmt.Sprintf("%s:%s", name, c.IP())
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(utils.ErrorResponse{
+				Message: "too many requests, please try again later",
+				Error:   nil,
+			})
+		},
+		Storage: &redisLimiterStorage{redisClient: redisClient},
+	})
+}
+
+// redisLimiterStorage is the fiber.Storage the limiter keeps its counters in.
+type redisLimiterStorage struct {
+	redisClient *redis.Client
+}
+
+func redisLimiterKey(key string) string {
+	return "rate_limit:" + key
+}
+
+func (s *redisLimiterStorage) Get(key string) ([]byte, error) {
+	value, err := s.redisClient.Get(context.Background(), redisLimiterKey(key)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+func (s *redisLimiterStorage) Set(key string, value []byte, exp time.Duration) error {
+	return s.redisClient.Set(context.Background(), redisLimiterKey(key), value, exp).Err()
+}
+
+func (s *redisLimiterStorage) Delete(key string) error {
+	return s.redisClient.Del(context.Background(), redisLimiterKey(key)).Err()
+}
+
+func (s *redisLimiterStorage) Reset() error {
+	ctx := context.Background()
+	iter := s.redisClient.Scan(ctx, 0, redisLimiterKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		if err := s.redisClient.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// Close leaves the client open, it is shared with the rest of the api.
+func (s *redisLimiterStorage) Close() error {
+	return nil
+}
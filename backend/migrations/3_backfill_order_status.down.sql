@@ -0,0 +1 @@
+UPDATE orders SET status = 'AWAITING_PAYMENT' WHERE status IN ('SHIPPED', 'LEGACY');
@@ -0,0 +1,2 @@
+UPDATE orders SET status = 'SHIPPED' WHERE tracking_number <> 'wait for tracking number' AND status = 'AWAITING_PAYMENT';
+UPDATE orders SET status = 'LEGACY' WHERE status = 'AWAITING_PAYMENT';
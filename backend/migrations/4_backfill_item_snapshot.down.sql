@@ -0,0 +1 @@
+UPDATE items SET unit_price = 0, brand_name = '', model_name = '', os = '';
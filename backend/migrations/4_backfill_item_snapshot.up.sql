@@ -0,0 +1,8 @@
+UPDATE items
+JOIN carts ON items.cart_id = carts.id
+JOIN phones ON phones.id = items.phone_id
+SET items.unit_price = phones.price,
+    items.brand_name = phones.brand_name,
+    items.model_name = phones.model_name,
+    items.os = phones.os
+WHERE carts.status = 'CONFIRMED';
@@ -0,0 +1,5 @@
+ALTER TABLE phones MODIFY price FLOAT DEFAULT 0;
+ALTER TABLE orders MODIFY total_price FLOAT;
+ALTER TABLE items MODIFY unit_price FLOAT DEFAULT 0;
+ALTER TABLE payments MODIFY amount FLOAT;
+ALTER TABLE returns MODIFY refund_amount FLOAT DEFAULT 0;
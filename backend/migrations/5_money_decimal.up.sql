@@ -0,0 +1,5 @@
+ALTER TABLE phones MODIFY price DECIMAL(12,2) DEFAULT 0;
+ALTER TABLE orders MODIFY total_price DECIMAL(12,2) DEFAULT 0;
+ALTER TABLE items MODIFY unit_price DECIMAL(12,2) DEFAULT 0;
+ALTER TABLE payments MODIFY amount DECIMAL(12,2);
+ALTER TABLE returns MODIFY refund_amount DECIMAL(12,2) DEFAULT 0;
@@ -0,0 +1 @@
+UPDATE orders SET subtotal = 0, vat_amount = 0 WHERE vat_rate = 0;
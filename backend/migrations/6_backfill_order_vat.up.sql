@@ -0,0 +1 @@
+UPDATE orders SET subtotal = total_price, vat_amount = 0 WHERE subtotal = 0 AND vat_rate = 0;
@@ -0,0 +1,2 @@
+DELETE FROM shipping_rates WHERE carrier_id IN (SELECT id FROM shipping_carriers WHERE code IN ('THP', 'KERRY'));
+DELETE FROM shipping_carriers WHERE code IN ('THP', 'KERRY');
@@ -0,0 +1,20 @@
+UPDATE phones SET weight = 400, width = 100, height = 180, depth = 60 WHERE weight = 0;
+
+INSERT INTO shipping_carriers (code, name, active, free_shipping_threshold, created_at, updated_at)
+VALUES
+('THP', 'Thailand Post EMS', TRUE, 10000.00, NOW(), NOW()),
+('KERRY', 'Kerry Express', TRUE, 20000.00, NOW(), NOW());
+
+INSERT INTO shipping_rates (carrier_id, zone, max_weight, fee, created_at, updated_at)
+SELECT id, 'BANGKOK', 1000, 40.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'THP'
+UNION ALL SELECT id, 'BANGKOK', 3000, 60.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'THP'
+UNION ALL SELECT id, 'BANGKOK', 10000, 120.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'THP'
+UNION ALL SELECT id, 'PROVINCE', 1000, 60.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'THP'
+UNION ALL SELECT id, 'PROVINCE', 3000, 90.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'THP'
+UNION ALL SELECT id, 'PROVINCE', 10000, 180.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'THP'
+UNION ALL SELECT id, 'BANGKOK', 1000, 50.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'KERRY'
+UNION ALL SELECT id, 'BANGKOK', 3000, 70.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'KERRY'
+UNION ALL SELECT id, 'BANGKOK', 10000, 140.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'KERRY'
+UNION ALL SELECT id, 'PROVINCE', 1000, 70.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'KERRY'
+UNION ALL SELECT id, 'PROVINCE', 3000, 100.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'KERRY'
+UNION ALL SELECT id, 'PROVINCE', 10000, 200.00, NOW(), NOW() FROM shipping_carriers WHERE code = 'KERRY';
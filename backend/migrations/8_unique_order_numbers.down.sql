@@ -0,0 +1,4 @@
+DROP INDEX idx_orders_order_number ON orders;
+DROP INDEX idx_orders_tracking_number ON orders;
+UPDATE orders SET tracking_number = 'wait for tracking number' WHERE tracking_number IS NULL;
+ALTER TABLE orders MODIFY tracking_number VARCHAR(50) NOT NULL;
@@ -0,0 +1,4 @@
+UPDATE orders SET tracking_number = NULL WHERE tracking_number = 'wait for tracking number' OR tracking_number = '';
+ALTER TABLE orders MODIFY tracking_number VARCHAR(50) NULL DEFAULT NULL;
+CREATE UNIQUE INDEX idx_orders_tracking_number ON orders (tracking_number);
+CREATE UNIQUE INDEX idx_orders_order_number ON orders (order_number);
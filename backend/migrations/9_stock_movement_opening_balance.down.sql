@@ -0,0 +1 @@
+DELETE FROM stock_movements WHERE type = 'ADJUSTMENT' AND note = 'opening balance' AND created_by_id IS NULL;
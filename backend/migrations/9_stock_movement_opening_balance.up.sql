@@ -0,0 +1,2 @@
+INSERT INTO stock_movements (phone_id, type, quantity, balance_after, note, created_at)
+SELECT id, 'ADJUSTMENT', amount, amount, 'opening balance', NOW(3) FROM phones WHERE amount <> 0;
@@ -12,6 +12,11 @@ type Item struct {
 	PhoneID   uint           `json:"phone_id"`
 	Phone     Phone          `gorm:"constraint:OnDelete:CASCADE;" json:"phone"`
 	CartID    uint           `json:"cart_id"`
+	UnitPrice Money          `gorm:"unit_price;type:decimal(12,2);default:0" json:"unit_price"` // snapshot of the phone at checkout
+	UnitCost  Money          `gorm:"unit_cost;type:decimal(12,2);default:0" json:"-"`           // cost price at checkout , 0 when it was unknown
+	BrandName string         `gorm:"brand_name" json:"brand_name"`
+	ModelName string         `gorm:"model_name" json:"model_name"`
+	OS        string         `gorm:"os" json:"os"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
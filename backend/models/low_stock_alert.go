@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	LowStockAlertStatusOpen     = "OPEN"
+	LowStockAlertStatusResolved = "RESOLVED"
+)
+
+// LowStockAlert is raised once when a phone drops below its reorder point and resolved when it is back up,
+// so staff are told about each shortage one time.
+type LowStockAlert struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	PhoneID      uint       `gorm:"phone_id;index;not null" json:"phone_id"`
+	Amount       int        `gorm:"amount;not null" json:"amount"` // stock when the alert was raised
+	ReorderPoint int        `gorm:"reorder_point;not null" json:"reorder_point"`
+	Status       string     `gorm:"status;size:16;default:'OPEN';index" json:"status"` // OPEN , RESOLVED
+	ResolvedAt   *time.Time `gorm:"resolved_at" json:"resolved_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrMoneyInvalid = errors.New("money amount invalid")
+
+// Money is an amount of baht kept as integer satang so sums and multiplications never drift.
+// It is stored as DECIMAL(12,2) and written to JSON as a number with two decimals.
+type Money int64
+
+func NewMoneyFromBaht(baht int64) Money {
+	return Money(baht * 100)
+}
+
+// ParseMoney parses a baht amount such as "15999", "15999.5" or "15999.50".
+func ParseMoney(value string) (Money, error) {
+	hundredths, ok := parseHundredths(value)
+	if !ok {
+		return 0, ErrMoneyInvalid
+	}
+
+	return Money(hundredths), nil
+}
+
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+func (m Money) String() string {
+	return formatHundredths(int64(m))
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+
+	return m.UnmarshalText([]byte(value))
+}
+
+// UnmarshalText lets form and query parsers decode Money fields.
+func (m *Money) UnmarshalText(data []byte) error {
+	money, err := ParseMoney(string(data))
+	if err != nil {
+		return err
+	}
+
+	*m = money
+	return nil
+}
+
+func (m *Money) Scan(value interface{}) error {
+	hundredths, err := scanHundredths(value)
+	if err != nil {
+		return fmt.Errorf("cannot scan %T into money : %w", value, err)
+	}
+
+	*m = Money(hundredths)
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// parseHundredths parses a decimal with at most two places, e.g. "7", "7.5" or "-7.50", into hundredths.
+func parseHundredths(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if len(fraction) > 2 {
+		fraction = strings.TrimRight(fraction, "0")
+	}
+	if whole == "" || len(fraction) > 2 {
+		return 0, false
+	}
+
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	hundredths, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil || hundredths < 0 {
+		return 0, false
+	}
+
+	hundredths += units * 100
+	if negative {
+		hundredths = -hundredths
+	}
+
+	return hundredths, true
+}
+
+func formatHundredths(hundredths int64) string {
+	sign := ""
+	if hundredths < 0 {
+		sign = "-"
+		hundredths = -hundredths
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, hundredths/100, hundredths%100)
+}
+
+// scanHundredths reads a DECIMAL(x,2) column into hundredths.
+func scanHundredths(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return scanHundredths(string(v))
+	case string:
+		hundredths, ok := parseHundredths(v)
+		if !ok {
+			return 0, fmt.Errorf("invalid decimal %q", v)
+		}
+		return hundredths, nil
+	case int64:
+		return v * 100, nil
+	case float64:
+		return int64(math.Round(v * 100)), nil
+	case float32:
+		return int64(math.Round(float64(v) * 100)), nil
+	default:
+		return 0, errors.New("unsupported type")
+	}
+}
@@ -6,13 +6,61 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	OrderStatusAwaitingPayment = "AWAITING_PAYMENT"
+	OrderStatusPaid            = "PAID"
+	OrderStatusPacking         = "PACKING"
+	OrderStatusShipped         = "SHIPPED"
+	OrderStatusDelivered       = "DELIVERED"
+	OrderStatusCancelled       = "CANCELLED"
+	OrderStatusRefunded        = "REFUNDED"
+	OrderStatusLegacy          = "LEGACY" // placed before the lifecycle existed , stock was taken outside the ledger
+)
+
+// OrderStatusTransitions lists the statuses an order may move to from each status.
+var OrderStatusTransitions = map[string][]string{
+	OrderStatusAwaitingPayment: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:            {OrderStatusPacking, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusPacking:         {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusShipped:         {OrderStatusDelivered, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusDelivered:       {OrderStatusRefunded},
+	OrderStatusCancelled:       {OrderStatusRefunded},
+	OrderStatusRefunded:        {},
+	OrderStatusLegacy:          {OrderStatusPacking, OrderStatusShipped, OrderStatusCancelled},
+}
+
 type Order struct {
-	ID             uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
-	TrackingNumber string         `gorm:"tracking_number;not null" json:"tracking_number"`
-	CartID         uint           `json:"cart_id"`
-	Cart           Cart           `json:"cart"`
-	TotalPrice     float32        `json:"total_price"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	ID              uint                 `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	OrderNumber     string               `gorm:"order_number;size:32;default:null" json:"order_number"`       // unique , index created by migration 8
+	TrackingNumber  string               `gorm:"tracking_number;size:50;default:null" json:"tracking_number"` // unique once shipped , null before
+	Status          string               `gorm:"status;default:'AWAITING_PAYMENT'" json:"status"`             // AWAITING_PAYMENT , PAID , PACKING , SHIPPED , DELIVERED , CANCELLED , REFUNDED , LEGACY
+	CartID          uint                 `json:"cart_id"`
+	Cart            Cart                 `json:"cart"`
+	ShippingCarrier string               `gorm:"shipping_carrier;size:32" json:"shipping_carrier"` // carrier code
+	ShippingZone    string               `gorm:"shipping_zone;size:16" json:"shipping_zone"`
+	ShippingWeight  int                  `gorm:"shipping_weight;default:0" json:"shipping_weight"` // chargeable grams
+	ShippingFee     Money                `gorm:"shipping_fee;type:decimal(12,2);default:0" json:"shipping_fee"`
+	Subtotal        Money                `gorm:"subtotal;type:decimal(12,2);default:0" json:"subtotal"` // price before vat , shipping included
+	VATRate         Rate                 `gorm:"vat_rate;type:decimal(5,2);default:0" json:"vat_rate"`  // percent , e.g. 7.00
+	VATInclusive    bool                 `gorm:"vat_inclusive;default:true" json:"vat_inclusive"`
+	VATAmount       Money                `gorm:"vat_amount;type:decimal(12,2);default:0" json:"vat_amount"`
+	TotalPrice      Money                `gorm:"total_price;type:decimal(12,2);default:0" json:"total_price"`
+	CancelReason    string               `gorm:"cancel_reason" json:"cancel_reason"`
+	StatusHistories []OrderStatusHistory `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE;" json:"status_histories,omitempty"`
+	Payments        []Payment            `gorm:"foreignKey:OrderID" json:"payments,omitempty"`
+	PaymentSlips    []PaymentSlip        `gorm:"foreignKey:OrderID" json:"payment_slips,omitempty"`
+	ShipmentEvents  []ShipmentEvent      `gorm:"foreignKey:OrderID" json:"shipment_events,omitempty"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt       `gorm:"index" json:"deleted_at"`
+}
+
+func (o Order) CanTransitionTo(status string) bool {
+	for _, next := range OrderStatusTransitions[o.Status] {
+		if next == status {
+			return true
+		}
+	}
+
+	return false
 }
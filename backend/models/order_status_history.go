@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type OrderStatusHistory struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	OrderID     uint      `gorm:"index" json:"order_id"`
+	FromStatus  string    `gorm:"from_status" json:"from_status"`
+	ToStatus    string    `gorm:"to_status;not null" json:"to_status"`
+	Note        string    `gorm:"note" json:"note"`
+	ChangedByID *uint     `json:"changed_by_id"` // nil when changed by the system , e.g. payment webhook
+	ChangedBy   *User     `gorm:"foreignKey:ChangedByID" json:"changed_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
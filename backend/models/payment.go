@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentStatusPending   = "PENDING"
+	PaymentStatusSucceeded = "SUCCEEDED"
+	PaymentStatusFailed    = "FAILED"
+	PaymentStatusRefunded  = "REFUNDED"
+)
+
+type Payment struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	OrderID     uint           `gorm:"index" json:"order_id"`
+	Provider    string         `gorm:"provider;not null" json:"provider"` // mock , promptpay
+	ProviderRef string         `gorm:"provider_ref;uniqueIndex;size:64;not null" json:"provider_ref"`
+	Amount      Money          `gorm:"amount;type:decimal(12,2)" json:"amount"`
+	Status      string         `gorm:"status;default:'PENDING'" json:"status"` // PENDING , SUCCEEDED , FAILED , REFUNDED
+	Payload     string         `gorm:"payload;type:text" json:"payload"`       // data the customer needs to pay , e.g. promptpay qr payload
+	SlipRef     string         `gorm:"slip_ref" json:"slip_ref"`               // bank slip reference of a transfer confirmed by an admin
+	PaidAt      *time.Time     `json:"paid_at"`
+	RefundedAt  *time.Time     `json:"refunded_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentSlipStatusPending  = "PENDING"
+	PaymentSlipStatusApproved = "APPROVED"
+	PaymentSlipStatusRejected = "REJECTED"
+)
+
+type PaymentSlip struct {
+	ID           uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	OrderID      uint           `gorm:"index" json:"order_id"`
+	UploadedByID uint           `json:"uploaded_by_id"`
+	Image        []byte         `gorm:"image;type:longblob" json:"-"`
+	ContentType  string         `gorm:"content_type" json:"content_type"`
+	Status       string         `gorm:"status;default:'PENDING';index" json:"status"` // PENDING , APPROVED , REJECTED
+	RejectReason string         `gorm:"reject_reason" json:"reject_reason"`
+	ReviewedByID *uint          `json:"reviewed_by_id"`
+	ReviewedAt   *time.Time     `json:"reviewed_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
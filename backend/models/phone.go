@@ -7,14 +7,25 @@ import (
 )
 
 type Phone struct {
-	ID        uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
-	Price     float32        `gorm:"price;default:0" json:"price"`
-	BrandName string         `gorm:"bland_name" json:"brand_name"`
-	ModelName string         `gorm:"model_name" json:"model_name"`
-	OS        string         `gorm:"os" json:"os"`
-	Amount    int            `gorm:"amount;default:0" json:"amount"`
-	Image     []byte         `gorm:"image;type:longblob" json:"image"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	ID            uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Price         Money          `gorm:"price;type:decimal(12,2);default:0" json:"price"`
+	CostPrice     Money          `gorm:"cost_price;type:decimal(12,2);default:0" json:"-"` // moving average of purchase order receipts , kept off the public listing
+	BrandName     string         `gorm:"bland_name" json:"brand_name"`
+	ModelName     string         `gorm:"model_name" json:"model_name"`
+	OS            string         `gorm:"os" json:"os"`
+	Barcode       *string        `gorm:"barcode;size:64;uniqueIndex" json:"barcode"` // scanned during stock takes
+	Amount        int            `gorm:"amount;default:0" json:"amount"`
+	ReorderPoint  int            `gorm:"reorder_point;default:0" json:"reorder_point"` // alert below this amount , 0 = never
+	Weight        int            `gorm:"weight;default:0" json:"weight"`               // grams
+	Width         int            `gorm:"width;default:0" json:"width"`                 // mm
+	Height        int            `gorm:"height;default:0" json:"height"`               // mm
+	Depth         int            `gorm:"depth;default:0" json:"depth"`                 // mm
+	Image         []byte         `gorm:"image;type:longblob" json:"image"`
+	Reserved      int            `gorm:"-" json:"reserved"`  // held in carts , filled by the phone listing
+	Available     int            `gorm:"-" json:"available"` // amount minus reserved
+	Units         []PhoneUnit    `gorm:"foreignKey:PhoneID" json:"units,omitempty"`
+	WarrantyTerms []WarrantyTerm `gorm:"foreignKey:PhoneID" json:"warranty_terms,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	PhoneUnitStatusInStock  = "IN_STOCK"
+	PhoneUnitStatusReserved = "RESERVED" // assigned to an order line while packing
+	PhoneUnitStatusSold     = "SOLD"
+	PhoneUnitStatusReturned = "RETURNED" // came back and was not restocked
+)
+
+// PhoneUnit is one physical handset of a phone with the order line it is on now.
+// Restocking a returned unit clears the line, its status histories keep every line it went out on.
+type PhoneUnit struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	PhoneID   uint       `gorm:"phone_id;index;not null" json:"phone_id"`
+	IMEI      string     `gorm:"column:imei;size:15;uniqueIndex;not null" json:"imei"`
+	Serial    string     `gorm:"serial;size:64" json:"serial"`
+	Status    string     `gorm:"status;size:16;default:'IN_STOCK';index" json:"status"` // IN_STOCK , RESERVED , SOLD , RETURNED
+	ItemID    *uint      `gorm:"item_id;index" json:"item_id"`
+	OrderID   *uint      `gorm:"order_id;index" json:"order_id"`
+	SoldAt    *time.Time `gorm:"sold_at" json:"sold_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// PhoneUnitStatusHistory is appended on every status change of a unit and never updated,
+// ItemID and OrderID are the order line the change was about.
+type PhoneUnitStatusHistory struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	PhoneUnitID uint      `gorm:"index" json:"phone_unit_id"`
+	FromStatus  string    `gorm:"from_status" json:"from_status"`
+	ToStatus    string    `gorm:"to_status;not null" json:"to_status"`
+	ItemID      *uint     `gorm:"item_id" json:"item_id"`
+	OrderID     *uint     `gorm:"order_id;index" json:"order_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
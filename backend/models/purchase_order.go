@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	PurchaseOrderStatusDraft             = "DRAFT"
+	PurchaseOrderStatusSent              = "SENT"
+	PurchaseOrderStatusPartiallyReceived = "PARTIALLY_RECEIVED"
+	PurchaseOrderStatusReceived          = "RECEIVED"
+	PurchaseOrderStatusCancelled         = "CANCELLED"
+)
+
+type PurchaseOrder struct {
+	ID          uint                `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	SupplierID  uint                `gorm:"supplier_id;index;not null" json:"supplier_id"`
+	Supplier    Supplier            `json:"supplier"`
+	Status      string              `gorm:"status;size:24;default:'DRAFT';index" json:"status"` // DRAFT , SENT , PARTIALLY_RECEIVED , RECEIVED , CANCELLED
+	Note        string              `gorm:"note" json:"note"`
+	Lines       []PurchaseOrderLine `gorm:"constraint:OnDelete:CASCADE;" json:"lines"`
+	CreatedByID uint                `gorm:"created_by_id;not null" json:"created_by_id"`
+	SentAt      *time.Time          `gorm:"sent_at" json:"sent_at"`
+	ReceivedAt  *time.Time          `gorm:"received_at" json:"received_at"` // when the last line came in
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+type PurchaseOrderLine struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	PurchaseOrderID  uint      `gorm:"purchase_order_id;index;not null" json:"purchase_order_id"`
+	PhoneID          uint      `gorm:"phone_id;index;not null" json:"phone_id"`
+	Phone            Phone     `gorm:"foreignKey:PhoneID" json:"phone"`
+	CostPrice        Money     `gorm:"cost_price;type:decimal(12,2);default:0" json:"cost_price"` // per unit
+	ExpectedQuantity int       `gorm:"expected_quantity;not null" json:"expected_quantity"`
+	ReceivedQuantity int       `gorm:"received_quantity;default:0" json:"received_quantity"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrRateInvalid = errors.New("rate invalid")
+
+// Rate is a percentage kept as integer basis points, 700 is 7 percent. It is its own type so a
+// rate cannot be added to a Money amount by mistake, Of is the only way to apply one.
+// It is stored as DECIMAL(5,2) percent and written to JSON as a percent with two decimals, e.g. 7.00.
+type Rate int64
+
+// ParseRate parses a percent such as "7", "7.5" or "7.50".
+func ParseRate(percent string) (Rate, error) {
+	basisPoints, ok := parseHundredths(percent)
+	if !ok {
+		return 0, ErrRateInvalid
+	}
+
+	return Rate(basisPoints), nil
+}
+
+// Of returns the rate of amount rounded half away from zero to the satang.
+func (r Rate) Of(amount Money) Money {
+	return Money(divideRound(int64(amount)*int64(r), 10000))
+}
+
+// IncludedIn returns the part of amount that is the rate when amount already includes it,
+// e.g. the VAT inside a VAT inclusive price.
+func (r Rate) IncludedIn(amount Money) Money {
+	return Money(divideRound(int64(amount)*int64(r), 10000+int64(r)))
+}
+
+func (r Rate) String() string {
+	return formatHundredths(int64(r))
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+
+	return r.UnmarshalText([]byte(value))
+}
+
+func (r *Rate) UnmarshalText(data []byte) error {
+	rate, err := ParseRate(string(data))
+	if err != nil {
+		return err
+	}
+
+	*r = rate
+	return nil
+}
+
+func (r *Rate) Scan(value interface{}) error {
+	basisPoints, err := scanHundredths(value)
+	if err != nil {
+		return fmt.Errorf("cannot scan %T into rate : %w", value, err)
+	}
+
+	*r = Rate(basisPoints)
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// divideRound divides rounding half away from zero.
+func divideRound(numerator int64, denominator int64) int64 {
+	if numerator < 0 {
+		return -divideRound(-numerator, denominator)
+	}
+
+	return (numerator + denominator/2) / denominator
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReturnStatusRequested = "REQUESTED"
+	ReturnStatusApproved  = "APPROVED"
+	ReturnStatusRejected  = "REJECTED"
+	ReturnStatusReceived  = "RECEIVED"
+	ReturnStatusCompleted = "COMPLETED"
+
+	ReturnResolutionRefund  = "REFUND"
+	ReturnResolutionReplace = "REPLACE"
+	ReturnResolutionRepair  = "REPAIR"
+)
+
+type Return struct {
+	ID            uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	OrderID       uint           `gorm:"index" json:"order_id"`
+	ItemID        uint           `gorm:"index" json:"item_id"`
+	Item          Item           `json:"item"`
+	Quantity      int            `gorm:"quantity;not null" json:"quantity"`
+	Reason        string         `gorm:"reason" json:"reason"`
+	Condition     string         `gorm:"condition" json:"condition"`                     // SEALED , OPENED , DAMAGED , FAULTY
+	Resolution    string         `gorm:"resolution;not null" json:"resolution"`          // REFUND , REPLACE , REPAIR
+	Status        string         `gorm:"status;default:'REQUESTED';index" json:"status"` // REQUESTED , APPROVED , REJECTED , RECEIVED , COMPLETED
+	AdminNote     string         `gorm:"admin_note" json:"admin_note"`
+	Restocked     bool           `gorm:"restocked;default:false" json:"restocked"`
+	RefundAmount  Money          `gorm:"refund_amount;type:decimal(12,2);default:0" json:"refund_amount"`
+	RequestedByID uint           `json:"requested_by_id"`
+	ReviewedByID  *uint          `json:"reviewed_by_id"`
+	ReceivedAt    *time.Time     `json:"received_at"`
+	CompletedAt   *time.Time     `gorm:"index" json:"completed_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	ShipmentStatusPickedUp       = "PICKED_UP"
+	ShipmentStatusInTransit      = "IN_TRANSIT"
+	ShipmentStatusOutForDelivery = "OUT_FOR_DELIVERY"
+	ShipmentStatusFailedAttempt  = "FAILED_ATTEMPT"
+	ShipmentStatusDelivered      = "DELIVERED"
+	ShipmentStatusReturned       = "RETURNED"
+)
+
+// ShipmentEvent is one scan reported by a carrier, carriers resend events so
+// the carrier, tracking number, raw status and time together are unique.
+type ShipmentEvent struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	OrderID        uint      `gorm:"order_id;index;not null" json:"order_id"`
+	Carrier        string    `gorm:"carrier;size:32;not null;uniqueIndex:idx_shipment_event" json:"carrier"`
+	TrackingNumber string    `gorm:"tracking_number;size:50;not null;uniqueIndex:idx_shipment_event" json:"tracking_number"`
+	Status         string    `gorm:"status;size:32;not null" json:"status"` // PICKED_UP , IN_TRANSIT , OUT_FOR_DELIVERY , FAILED_ATTEMPT , DELIVERED , RETURNED
+	RawStatus      string    `gorm:"raw_status;size:32;not null;uniqueIndex:idx_shipment_event" json:"raw_status"`
+	Description    string    `gorm:"description" json:"description"`
+	Location       string    `gorm:"location" json:"location"`
+	OccurredAt     time.Time `gorm:"occurred_at;not null;uniqueIndex:idx_shipment_event" json:"occurred_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ShippingZoneBangkok  = "BANGKOK"
+	ShippingZoneProvince = "PROVINCE"
+)
+
+type ShippingCarrier struct {
+	ID                    uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Code                  string         `gorm:"code;uniqueIndex;size:32;not null" json:"code"`
+	Name                  string         `gorm:"name;not null" json:"name"`
+	Active                bool           `gorm:"active;default:true" json:"active"`
+	FreeShippingThreshold Money          `gorm:"free_shipping_threshold;type:decimal(12,2);default:0" json:"free_shipping_threshold"` // 0 = never free
+	Rates                 []ShippingRate `gorm:"foreignKey:CarrierID;constraint:OnDelete:CASCADE;" json:"rates,omitempty"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
+
+// ShippingRate is one weight bracket of a carrier in a zone, it covers parcels up to MaxWeight grams.
+type ShippingRate struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	CarrierID uint      `gorm:"carrier_id;index;not null" json:"carrier_id"`
+	Zone      string    `gorm:"zone;size:16;not null" json:"zone"` // BANGKOK , PROVINCE
+	MaxWeight int       `gorm:"max_weight;not null" json:"max_weight"`
+	Fee       Money     `gorm:"fee;type:decimal(12,2);default:0" json:"fee"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	StockMovementReceipt      = "RECEIPT"
+	StockMovementSale         = "SALE"
+	StockMovementCancellation = "CANCELLATION"
+	StockMovementReturn       = "RETURN"
+	StockMovementAdjustment   = "ADJUSTMENT"
+	StockMovementStockTake    = "STOCK_TAKE"
+)
+
+// StockMovement is one line of the inventory ledger, the quantities of a phone sum up to phones.amount.
+type StockMovement struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	PhoneID         uint      `gorm:"phone_id;index;not null" json:"phone_id"`
+	Type            string    `gorm:"type;size:16;not null" json:"type"` // RECEIPT , SALE , CANCELLATION , RETURN , ADJUSTMENT , STOCK_TAKE
+	Quantity        int       `gorm:"quantity;not null" json:"quantity"` // signed , negative takes stock out
+	BalanceAfter    int       `gorm:"balance_after;not null" json:"balance_after"`
+	OrderID         *uint     `gorm:"order_id;index" json:"order_id"`
+	ReturnID        *uint     `gorm:"return_id;index" json:"return_id"`
+	StockTakeID     *uint     `gorm:"stock_take_id;index" json:"stock_take_id"`
+	PurchaseOrderID *uint     `gorm:"purchase_order_id;index" json:"purchase_order_id"`
+	Note            string    `gorm:"note" json:"note"`
+	CreatedByID     *uint     `gorm:"created_by_id" json:"created_by_id"` // nil = system
+	CreatedAt       time.Time `gorm:"index" json:"created_at"`
+}
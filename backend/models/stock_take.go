@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	StockTakeStatusOpen      = "OPEN"
+	StockTakeStatusPosted    = "POSTED"
+	StockTakeStatusCancelled = "CANCELLED"
+)
+
+// StockTake is one shelf count, the lines are editable while it is open and frozen once posted.
+type StockTake struct {
+	ID         uint            `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Status     string          `gorm:"status;size:16;default:'OPEN';index" json:"status"` // OPEN , POSTED , CANCELLED
+	Note       string          `gorm:"note" json:"note"`
+	Reason     string          `gorm:"reason" json:"reason"` // given when posting , copied to the stock movements
+	OpenedByID uint            `gorm:"opened_by_id;not null" json:"opened_by_id"`
+	PostedByID *uint           `gorm:"posted_by_id" json:"posted_by_id"`
+	PostedAt   *time.Time      `gorm:"posted_at" json:"posted_at"`
+	Lines      []StockTakeLine `json:"lines"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// StockTakeLine is the counted quantity of one phone. ExpectedAmount is phones.amount when the phone was first counted,
+// posting applies Variance so stock that moved after the count is not undone.
+type StockTakeLine struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	StockTakeID    uint      `gorm:"stock_take_id;uniqueIndex:idx_stock_take_line;not null" json:"stock_take_id"`
+	PhoneID        uint      `gorm:"phone_id;uniqueIndex:idx_stock_take_line;not null" json:"phone_id"`
+	Phone          Phone     `gorm:"foreignKey:PhoneID" json:"phone"`
+	CountedAmount  int       `gorm:"counted_amount;not null" json:"counted_amount"`
+	ExpectedAmount int       `gorm:"expected_amount;default:0" json:"expected_amount"`
+	Variance       int       `gorm:"variance;default:0" json:"variance"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
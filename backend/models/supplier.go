@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Supplier struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Name        string         `gorm:"name;size:100;not null" json:"name"`
+	ContactName string         `gorm:"contact_name" json:"contact_name"`
+	Email       string         `gorm:"email" json:"email"`
+	PhoneNumber string         `gorm:"phone_number" json:"phone_number"`
+	Address     string         `gorm:"address" json:"address"`
+	TaxID       string         `gorm:"tax_id;size:13" json:"tax_id"`
+	Active      bool           `gorm:"active;default:true" json:"active"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type TaxInvoice struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Number       string    `gorm:"number;uniqueIndex;size:32;not null" json:"number"`
+	OrderID      uint      `gorm:"uniqueIndex" json:"order_id"`
+	BuyerName    string    `gorm:"buyer_name" json:"buyer_name"`
+	BuyerAddress string    `gorm:"buyer_address" json:"buyer_address"`
+	BuyerTaxID   string    `gorm:"buyer_tax_id" json:"buyer_tax_id"`
+	BuyerBranch  string    `gorm:"buyer_branch" json:"buyer_branch"`
+	Subtotal     Money     `gorm:"subtotal;type:decimal(12,2)" json:"subtotal"`
+	VATRate      Rate      `gorm:"vat_rate;type:decimal(5,2)" json:"vat_rate"`
+	VATAmount    Money     `gorm:"vat_amount;type:decimal(12,2)" json:"vat_amount"`
+	Total        Money     `gorm:"total;type:decimal(12,2)" json:"total"`
+	IssuedByID   uint      `json:"issued_by_id"`
+	IssuedAt     time.Time `json:"issued_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TaxInvoiceSequence holds the last number issued per year, invoices are numbered
+// from it under a row lock so a number is never skipped or used twice.
+type TaxInvoiceSequence struct {
+	Year       int `gorm:"primaryKey;autoIncrement:false" json:"year"`
+	LastNumber int `gorm:"last_number;not null;default:0" json:"last_number"`
+}
@@ -15,6 +15,8 @@ type User struct {
 	Password    string         `gorm:"password;not null" json:"password"`
 	LineID      string         `gorm:"line_id" json:"line_id"`
 	Address     string         `gorm:"address" json:"address"`
+	TaxID       string         `gorm:"tax_id" json:"tax_id"`         // buyer tax id for full tax invoices
+	TaxBranch   string         `gorm:"tax_branch" json:"tax_branch"` // 00000 for head office
 	Age         int            `gorm:"age" json:"age"`
 	BirthDate   time.Time      `gorm:"birth_date" json:"birth_date"`
 	Role        string         `gorm:"role;default:'guess'" json:"role"` // guess , admin
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	WarrantyTypeManufacturer = "MANUFACTURER"
+	WarrantyTypeShop         = "SHOP"
+)
+
+// WarrantyTerm is one warranty a phone is sold with, a phone has at most one term per type.
+type WarrantyTerm struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	PhoneID   uint      `gorm:"phone_id;uniqueIndex:idx_warranty_term;not null" json:"phone_id"`
+	Type      string    `gorm:"type;size:16;uniqueIndex:idx_warranty_term;not null" json:"type"` // MANUFACTURER , SHOP
+	Months    int       `gorm:"months;not null" json:"months"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Warranty is created for each term of each order line when the order is delivered,
+// it covers Quantity handsets from StartsAt until ExpiresAt. Refunded handsets are taken off Quantity
+// and ExpiresAt is brought forward when nothing is left covered or the order is refunded.
+type Warranty struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	UserID    uint      `gorm:"user_id;index;not null" json:"user_id"`
+	OrderID   uint      `gorm:"order_id;index;not null" json:"order_id"`
+	ItemID    uint      `gorm:"item_id;uniqueIndex:idx_warranty_item;not null" json:"item_id"`
+	Type      string    `gorm:"type;size:16;uniqueIndex:idx_warranty_item;not null" json:"type"` // MANUFACTURER , SHOP
+	PhoneID   uint      `gorm:"phone_id;index;not null" json:"phone_id"`
+	BrandName string    `gorm:"brand_name" json:"brand_name"`
+	ModelName string    `gorm:"model_name" json:"model_name"`
+	Quantity  int       `gorm:"quantity;not null" json:"quantity"`
+	Months    int       `gorm:"months;not null" json:"months"`
+	StartsAt  time.Time `gorm:"starts_at;not null" json:"starts_at"`
+	ExpiresAt time.Time `gorm:"expires_at;index;not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (w Warranty) IsActive(now time.Time) bool {
+	return now.Before(w.ExpiresAt)
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+const (
+	WarrantyClaimStatusSubmitted       = "SUBMITTED"
+	WarrantyClaimStatusReceived        = "RECEIVED"
+	WarrantyClaimStatusAtServiceCenter = "AT_SERVICE_CENTER"
+	WarrantyClaimStatusReturned        = "RETURNED"
+	WarrantyClaimStatusRejected        = "REJECTED"
+)
+
+// WarrantyClaimStatusTransitions lists the statuses a claim may move to from each status.
+var WarrantyClaimStatusTransitions = map[string][]string{
+	WarrantyClaimStatusSubmitted:       {WarrantyClaimStatusReceived, WarrantyClaimStatusRejected},
+	WarrantyClaimStatusReceived:        {WarrantyClaimStatusAtServiceCenter, WarrantyClaimStatusReturned, WarrantyClaimStatusRejected},
+	WarrantyClaimStatusAtServiceCenter: {WarrantyClaimStatusReturned},
+	WarrantyClaimStatusReturned:        {},
+	WarrantyClaimStatusRejected:        {},
+}
+
+type WarrantyClaim struct {
+	ID              uint                         `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	WarrantyID      uint                         `gorm:"warranty_id;index;not null" json:"warranty_id"`
+	Warranty        Warranty                     `json:"warranty"`
+	UserID          uint                         `gorm:"user_id;index;not null" json:"user_id"`
+	IMEI            string                       `gorm:"column:imei;size:15" json:"imei"` // the handset , when the shop tracked it
+	Problem         string                       `gorm:"problem;not null" json:"problem"`
+	Status          string                       `gorm:"status;size:24;default:'SUBMITTED';index" json:"status"` // SUBMITTED , RECEIVED , AT_SERVICE_CENTER , RETURNED , REJECTED
+	ServiceCenter   string                       `gorm:"service_center" json:"service_center"`
+	StatusHistories []WarrantyClaimStatusHistory `gorm:"foreignKey:WarrantyClaimID;constraint:OnDelete:CASCADE;" json:"status_histories,omitempty"`
+	CreatedAt       time.Time                    `json:"created_at"`
+	UpdatedAt       time.Time                    `json:"updated_at"`
+}
+
+func (c WarrantyClaim) CanTransitionTo(status string) bool {
+	for _, next := range WarrantyClaimStatusTransitions[c.Status] {
+		if next == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WarrantyClaimStatusHistory struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	WarrantyClaimID uint      `gorm:"index" json:"warranty_claim_id"`
+	FromStatus      string    `gorm:"from_status" json:"from_status"`
+	ToStatus        string    `gorm:"to_status;not null" json:"to_status"`
+	Note            string    `gorm:"note" json:"note"`
+	ChangedByID     *uint     `json:"changed_by_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/BaimhonS/kab-phone/configs"
 	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
@@ -32,6 +33,28 @@ func MigrateUp() {
 		models.Cart{},
 		models.Item{},
 		models.Order{},
+		models.OrderStatusHistory{},
+		models.Payment{},
+		models.PaymentSlip{},
+		models.Return{},
+		models.TaxInvoice{},
+		models.TaxInvoiceSequence{},
+		models.ShippingCarrier{},
+		models.ShippingRate{},
+		models.ShipmentEvent{},
+		models.StockMovement{},
+		models.StockTake{},
+		models.StockTakeLine{},
+		models.LowStockAlert{},
+		models.Supplier{},
+		models.PurchaseOrder{},
+		models.PurchaseOrderLine{},
+		models.PhoneUnit{},
+		models.PhoneUnitStatusHistory{},
+		models.WarrantyTerm{},
+		models.Warranty{},
+		models.WarrantyClaim{},
+		models.WarrantyClaimStatusHistory{},
 	); err != nil {
 		log.Fatalf("error migrating database : %v", err)
 	}
@@ -55,6 +78,7 @@ func MigrateUp() {
 	}
 
 	MigrateImage(db)
+	MigrateOrderNumbers(db)
 
 	log.Println("migrate up success")
 }
@@ -136,3 +160,29 @@ func MigrateImage(db *gorm.DB) {
 		return nil
 	})
 }
+
+// MigrateOrderNumbers gives orders placed before order numbers existed one, the check symbol cannot be computed in sql.
+func MigrateOrderNumbers(db *gorm.DB) {
+	var orders []models.Order
+	if err := db.Unscoped().Model(&models.Order{}).Select("id", "created_at").Where("order_number IS NULL").Find(&orders).Error; err != nil {
+		log.Fatalf("error getting orders without order number : %v", err)
+	}
+
+	for _, order := range orders {
+		for attempt := 0; ; attempt++ {
+			orderNumber, err := utils.GenerateOrderNumber(order.CreatedAt.Year())
+			if err != nil {
+				log.Fatalf("error generating order number : %v", err)
+			}
+
+			err = db.Unscoped().Model(&models.Order{}).Where("id = ?", order.ID).Update("order_number", orderNumber).Error
+			if err == nil {
+				break
+			}
+
+			if attempt == 4 {
+				log.Fatalf("error updating order number : %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package scripts
+
+import (
+	"log"
+	"os"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+type stockDrift struct {
+	ID           uint
+	ModelName    string
+	Amount       int
+	LedgerAmount int
+}
+
+// ReconcileStock compares phones.amount with the sum of the stock movement ledger and logs every phone
+// where they drifted apart, it exits with status 1 when any did so it can run from cron.
+func ReconcileStock() {
+	db := configs.ConnectDB()
+
+	var drifts []stockDrift
+	if err := db.Unscoped().Model(&models.Phone{}).
+		Select("phones.id, phones.model_name, phones.amount, COALESCE(ledger.amount, 0) AS ledger_amount").
+		Joins("LEFT JOIN (SELECT phone_id, SUM(quantity) AS amount FROM stock_movements GROUP BY phone_id) ledger ON ledger.phone_id = phones.id").
+		Where("phones.amount <> COALESCE(ledger.amount, 0)").
+		Order("phones.id").
+		Scan(&drifts).Error; err != nil {
+		log.Fatalf("error reconciling stock : %v", err)
+	}
+
+	for _, drift := range drifts {
+		log.Printf("phone %d (%s) amount %d ledger %d drift %d", drift.ID, drift.ModelName, drift.Amount, drift.LedgerAmount, drift.Amount-drift.LedgerAmount)
+	}
+
+	if len(drifts) > 0 {
+		log.Printf("reconcile stock found %d phones out of balance", len(drifts))
+		os.Exit(1)
+	}
+
+	log.Println("reconcile stock success , ledger matches every phone")
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+)
+
+var (
+	ErrShipmentSignatureInvalid = errors.New("shipment webhook signature invalid")
+	ErrTrackingNumberInvalid    = errors.New("tracking number format invalid for carrier")
+)
+
+type ShipmentWebhookEvent struct {
+	TrackingNumber string    `json:"tracking_number"`
+	Status         string    `json:"status"`
+	RawStatus      string    `json:"raw_status"`
+	Description    string    `json:"description"`
+	Location       string    `json:"location"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+// Carrier is implemented by every delivery company the shop hands parcels to.
+// Code matches models.ShippingCarrier.Code so an order's carrier resolves to its adapter.
+type Carrier interface {
+	Code() string
+	ValidateTrackingNumber(trackingNumber string) bool
+	VerifyWebhook(body []byte, signature string) ([]ShipmentWebhookEvent, error)
+}
+
+func NewCarriers() map[string]Carrier {
+	secret := os.Getenv("CARRIER_WEBHOOK_SECRET")
+
+	carriers := []Carrier{
+		NewKerryCarrier(secret),
+		NewFlashCarrier(secret),
+		NewThailandPostCarrier(secret),
+	}
+
+	// the fake carrier takes any FAKE tracking number , shipments must never be booked on it in production
+	if utils.IsDevelopment() {
+		carriers = append(carriers, NewFakeCarrier(secret))
+	}
+
+	carrierByCode := make(map[string]Carrier, len(carriers))
+	for _, carrier := range carriers {
+		carrierByCode[carrier.Code()] = carrier
+	}
+
+	return carrierByCode
+}
+
+// bangkokLocation is fixed at UTC+7 so carrier times parse without the tz database, Thailand has no DST.
+var bangkokLocation = time.FixedZone("ICT", 7*60*60)
+
+// normalizeShipmentStatus maps a carrier status code to a models.ShipmentStatus*,
+// codes the adapter does not know are scans along the way.
+func normalizeShipmentStatus(statuses map[string]string, rawStatus string) string {
+	if status, ok := statuses[rawStatus]; ok {
+		return status
+	}
+
+	return models.ShipmentStatusInTransit
+}
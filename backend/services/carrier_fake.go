@@ -0,0 +1,53 @@
+package services
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+var fakeTrackingNumberPattern = regexp.MustCompile(`^FAKE\d{6,}$`)
+
+type (
+	// FakeCarrier accepts FAKE tracking numbers and webhooks already in the normalized
+	// event shape, it is meant for local development and tests and is only registered when APP_ENV is development or test.
+	FakeCarrier struct {
+		WebhookSecret string
+	}
+
+	fakeWebhookBody struct {
+		Events []ShipmentWebhookEvent `json:"events"`
+	}
+)
+
+func NewFakeCarrier(webhookSecret string) Carrier {
+	return &FakeCarrier{
+		WebhookSecret: webhookSecret,
+	}
+}
+
+func (c *FakeCarrier) Code() string {
+	return "FAKE"
+}
+
+func (c *FakeCarrier) ValidateTrackingNumber(trackingNumber string) bool {
+	return fakeTrackingNumberPattern.MatchString(trackingNumber)
+}
+
+func (c *FakeCarrier) VerifyWebhook(body []byte, signature string) ([]ShipmentWebhookEvent, error) {
+	if !verifyHMACSignature(c.WebhookSecret, body, signature) {
+		return nil, ErrShipmentSignatureInvalid
+	}
+
+	var webhook fakeWebhookBody
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, err
+	}
+
+	for i, event := range webhook.Events {
+		if event.RawStatus == "" {
+			webhook.Events[i].RawStatus = event.Status
+		}
+	}
+
+	return webhook.Events, nil
+}
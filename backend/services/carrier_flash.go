@@ -0,0 +1,74 @@
+package services
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+var flashTrackingNumberPattern = regexp.MustCompile(`^TH\d{10,12}[A-Z]?$`)
+
+var flashStatuses = map[string]string{
+	"1": models.ShipmentStatusPickedUp,
+	"2": models.ShipmentStatusInTransit,
+	"3": models.ShipmentStatusOutForDelivery,
+	"4": models.ShipmentStatusFailedAttempt,
+	"5": models.ShipmentStatusDelivered,
+	"7": models.ShipmentStatusReturned,
+}
+
+type (
+	FlashCarrier struct {
+		WebhookSecret string
+	}
+
+	flashWebhookBody struct {
+		Pno       string `json:"pno"`
+		State     int    `json:"state"`
+		StateText string `json:"state_text"`
+		RoutedAt  int64  `json:"routed_at"` // unix seconds
+		StoreName string `json:"store_name"`
+	}
+)
+
+func NewFlashCarrier(webhookSecret string) Carrier {
+	return &FlashCarrier{
+		WebhookSecret: webhookSecret,
+	}
+}
+
+func (c *FlashCarrier) Code() string {
+	return "FLASH"
+}
+
+func (c *FlashCarrier) ValidateTrackingNumber(trackingNumber string) bool {
+	return flashTrackingNumberPattern.MatchString(trackingNumber)
+}
+
+// VerifyWebhook decodes a Flash Express route push, which carries a single scan per request.
+func (c *FlashCarrier) VerifyWebhook(body []byte, signature string) ([]ShipmentWebhookEvent, error) {
+	if !verifyHMACSignature(c.WebhookSecret, body, signature) {
+		return nil, ErrShipmentSignatureInvalid
+	}
+
+	var webhook flashWebhookBody
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, err
+	}
+
+	rawStatus := strconv.Itoa(webhook.State)
+
+	return []ShipmentWebhookEvent{
+		{
+			TrackingNumber: webhook.Pno,
+			Status:         normalizeShipmentStatus(flashStatuses, rawStatus),
+			RawStatus:      rawStatus,
+			Description:    webhook.StateText,
+			Location:       webhook.StoreName,
+			OccurredAt:     time.Unix(webhook.RoutedAt, 0),
+		},
+	}, nil
+}
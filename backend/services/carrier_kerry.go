@@ -0,0 +1,81 @@
+package services
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+var kerryTrackingNumberPattern = regexp.MustCompile(`^[A-Z]{4}\d{9,12}$`)
+
+var kerryStatuses = map[string]string{
+	"PUP": models.ShipmentStatusPickedUp,
+	"ITR": models.ShipmentStatusInTransit,
+	"OFD": models.ShipmentStatusOutForDelivery,
+	"UND": models.ShipmentStatusFailedAttempt,
+	"POD": models.ShipmentStatusDelivered,
+	"RTS": models.ShipmentStatusReturned,
+}
+
+type (
+	KerryCarrier struct {
+		WebhookSecret string
+	}
+
+	kerryWebhookBody struct {
+		Req struct {
+			Status struct {
+				ConNo      string `json:"con_no"`
+				StatusCode string `json:"status_code"`
+				StatusDesc string `json:"status_desc"`
+				StatusDate string `json:"status_date"` // 2006-01-02 15:04:05 bangkok time
+				Location   string `json:"location"`
+			} `json:"status"`
+		} `json:"req"`
+	}
+)
+
+func NewKerryCarrier(webhookSecret string) Carrier {
+	return &KerryCarrier{
+		WebhookSecret: webhookSecret,
+	}
+}
+
+func (c *KerryCarrier) Code() string {
+	return "KERRY"
+}
+
+func (c *KerryCarrier) ValidateTrackingNumber(trackingNumber string) bool {
+	return kerryTrackingNumberPattern.MatchString(trackingNumber)
+}
+
+// VerifyWebhook decodes a Kerry status push, which carries a single scan per request.
+func (c *KerryCarrier) VerifyWebhook(body []byte, signature string) ([]ShipmentWebhookEvent, error) {
+	if !verifyHMACSignature(c.WebhookSecret, body, signature) {
+		return nil, ErrShipmentSignatureInvalid
+	}
+
+	var webhook kerryWebhookBody
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, err
+	}
+
+	status := webhook.Req.Status
+	occurredAt, err := time.ParseInLocation("2006-01-02 15:04:05", status.StatusDate, bangkokLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	return []ShipmentWebhookEvent{
+		{
+			TrackingNumber: status.ConNo,
+			Status:         normalizeShipmentStatus(kerryStatuses, status.StatusCode),
+			RawStatus:      status.StatusCode,
+			Description:    status.StatusDesc,
+			Location:       status.Location,
+			OccurredAt:     occurredAt,
+		},
+	}, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+// thailandPostTrackingNumberPattern is the UPU S10 format, e.g. EF123456785TH.
+var thailandPostTrackingNumberPattern = regexp.MustCompile(`^[A-Z]{2}(\d{8})(\d)TH$`)
+
+var thailandPostS10Weights = []int{8, 6, 4, 2, 3, 5, 9, 7}
+
+var thailandPostStatuses = map[string]string{
+	"101": models.ShipmentStatusPickedUp,
+	"103": models.ShipmentStatusPickedUp,
+	"203": models.ShipmentStatusReturned,
+	"301": models.ShipmentStatusOutForDelivery,
+	"401": models.ShipmentStatusFailedAttempt,
+	"501": models.ShipmentStatusDelivered,
+}
+
+type (
+	ThailandPostCarrier struct {
+		WebhookSecret string
+	}
+
+	thailandPostWebhookBody struct {
+		Items []struct {
+			Barcode           string `json:"barcode"`
+			Status            string `json:"status"`
+			StatusDescription string `json:"status_description"`
+			Location          string `json:"location"`
+			StatusDate        string `json:"status_date"` // 02/01/2567 15:04:05+07:00 in buddhist era
+		} `json:"items"`
+	}
+)
+
+func NewThailandPostCarrier(webhookSecret string) Carrier {
+	return &ThailandPostCarrier{
+		WebhookSecret: webhookSecret,
+	}
+}
+
+func (c *ThailandPostCarrier) Code() string {
+	return "THP"
+}
+
+// ValidateTrackingNumber checks the S10 format and its mod 11 check digit.
+func (c *ThailandPostCarrier) ValidateTrackingNumber(trackingNumber string) bool {
+	match := thailandPostTrackingNumberPattern.FindStringSubmatch(trackingNumber)
+	if match == nil {
+		return false
+	}
+
+	sum := 0
+	for i, digit := range match[1] {
+		sum += int(digit-'0') * thailandPostS10Weights[i]
+	}
+
+	checkDigit := 11 - sum%11
+	switch checkDigit {
+	case 10:
+		checkDigit = 0
+	case 11:
+		checkDigit = 5
+	}
+
+	return int(match[2][0]-'0') == checkDigit
+}
+
+// VerifyWebhook decodes a Thailand Post hook, which can batch scans of several items.
+func (c *ThailandPostCarrier) VerifyWebhook(body []byte, signature string) ([]ShipmentWebhookEvent, error) {
+	if !verifyHMACSignature(c.WebhookSecret, body, signature) {
+		return nil, ErrShipmentSignatureInvalid
+	}
+
+	var webhook thailandPostWebhookBody
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return nil, err
+	}
+
+	events := make([]ShipmentWebhookEvent, 0, len(webhook.Items))
+	for _, item := range webhook.Items {
+		occurredAt, err := parseBuddhistEraTime(item.StatusDate)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, ShipmentWebhookEvent{
+			TrackingNumber: item.Barcode,
+			Status:         normalizeShipmentStatus(thailandPostStatuses, item.Status),
+			RawStatus:      item.Status,
+			Description:    item.StatusDescription,
+			Location:       item.Location,
+			OccurredAt:     occurredAt,
+		})
+	}
+
+	return events, nil
+}
+
+// parseBuddhistEraTime parses "dd/mm/yyyy hh:mm:ss+07:00" where yyyy is 543 years ahead of the common era.
+func parseBuddhistEraTime(value string) (time.Time, error) {
+	date, clock, ok := strings.Cut(value, " ")
+	if !ok || len(date) != 10 {
+		return time.Time{}, fmt.Errorf("invalid thailand post status date %q", value)
+	}
+
+	var year int
+	if _, err := fmt.Sscanf(date[6:], "%d", &year); err != nil {
+		return time.Time{}, fmt.Errorf("invalid thailand post status date %q", value)
+	}
+
+	return time.Parse("02/01/2006 15:04:05-07:00", fmt.Sprintf("%s%04d %s", date[:6], year-543, clock))
+}
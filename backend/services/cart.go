@@ -8,12 +8,14 @@ import (
 	"github.com/BaimhonS/kab-phone/utils"
 	"github.com/BaimhonS/kab-phone/validates"
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 
 	"gorm.io/gorm"
 )
 
 type CartServiceImpl struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Redis *redis.Client
 }
 
 type CartService interface {
@@ -25,7 +27,8 @@ type CartService interface {
 
 func NewCartService(configClients configs.ConfigClients) CartService {
 	return &CartServiceImpl{
-		DB: configClients.DB,
+		DB:    configClients.DB,
+		Redis: configClients.Redis,
 	}
 }
 
@@ -52,27 +55,65 @@ func (s *CartServiceImpl) AddItemToCart(c *fiber.Ctx) error {
 		})
 	}
 
-	isExist := false
-	for i, item := range cart.Items {
-		if item.PhoneID == req.Item.PhoneID {
-			cart.Items[i].Amount += req.Item.Amount
-
-			if err := s.DB.Model(&models.Item{}).Where("id = ?", item.ID).Update("amount", cart.Items[i].Amount).Error; err != nil {
-				return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
-					Message: "update product to cart failed",
-					Error:   err,
-				})
-			}
+	var phone models.Phone
+	if err := s.DB.Model(&models.Phone{}).Omit("image").Where("id = ?", req.Item.PhoneID).First(&phone).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "phone not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone error",
+			Error:   err,
+		})
+	}
 
-			isExist = true
+	existingIndex := -1
+	previousAmount := 0
+	for i, item := range cart.Items {
+		if item.PhoneID == phone.ID {
+			existingIndex = i
+			previousAmount = item.Amount
 			break
 		}
 	}
+	amount := previousAmount + req.Item.Amount
 
-	if !isExist {
-		cart.Items = append(cart.Items, req.Item)
+	reserved, available, err := reserveStock(c.Context(), s.Redis, phone.ID, user.ID, amount, phone.Amount)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "reserve stock failed",
+			Error:   err,
+		})
+	}
+
+	if !reserved {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("only %d of this phone available", max(available, 0)),
+			Error:   nil,
+		})
+	}
+
+	if existingIndex >= 0 {
+		cart.Items[existingIndex].Amount = amount
+
+		if err := s.DB.Model(&models.Item{}).Where("id = ?", cart.Items[existingIndex].ID).Update("amount", amount).Error; err != nil {
+			restoreStockHold(c.Context(), s.Redis, phone, user.ID, previousAmount)
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "update product to cart failed",
+				Error:   err,
+			})
+		}
+	} else {
+		// only take the phone and amount from the body so an item id cannot pull another cart's item in
+		cart.Items = append(cart.Items, models.Item{
+			PhoneID: phone.ID,
+			Amount:  amount,
+		})
 
 		if err := s.DB.Save(&cart).Error; err != nil {
+			restoreStockHold(c.Context(), s.Redis, phone, user.ID, previousAmount)
 			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
 				Message: "add product to cart failed",
 				Error:   err,
@@ -109,9 +150,38 @@ func (s *CartServiceImpl) GetCart(c *fiber.Ctx) error {
 }
 
 func (s *CartServiceImpl) RemoveItemFromCart(c *fiber.Ctx) error {
-	if err := s.DB.Delete(&models.Item{}, c.Params("id")).Error; err != nil {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	item, ok := c.Locals("item").(models.Item)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local item not found",
+		})
+	}
+
+	result := s.DB.Scopes(scopePendingCartItem(s.DB, user.ID, item.ID)).Delete(&models.Item{})
+	if result.Error != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
 			Message: "remove product from cart failed",
+			Error:   result.Error,
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "item can no longer be changed",
+			Error:   nil,
+		})
+	}
+
+	if err := releaseStock(c.Context(), s.Redis, item.PhoneID, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "release stock failed",
 			Error:   err,
 		})
 	}
@@ -130,27 +200,57 @@ func (s *CartServiceImpl) UpdateItemFromCart(c *fiber.Ctx) error {
 		})
 	}
 
-	var Item models.Item
-	if err := s.DB.Model(&models.Item{}).Where("id = ?", c.Params("id")).Preload("Phone").First(&Item).Error; err != nil {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	item, ok := c.Locals("item").(models.Item)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local item not found",
+		})
+	}
+
+	var phone models.Phone
+	if err := s.DB.Model(&models.Phone{}).Omit("image").Where("id = ?", item.PhoneID).First(&phone).Error; err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
 			Message: "product not found",
 			Error:   err,
 		})
 	}
 
-	if Item.Phone.Amount < req.Amount {
-		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
-			Message: fmt.Sprintf("you can only buy max %v in one checkout", Item.Phone.Amount),
-			Error:   nil,
+	reserved, available, err := reserveStock(c.Context(), s.Redis, phone.ID, user.ID, req.Amount, phone.Amount)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "reserve stock failed",
+			Error:   err,
 		})
 	}
 
-	Item.Amount = req.Amount
+	if !reserved {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("you can only buy max %v in one checkout", max(available, 0)),
+			Error:   nil,
+		})
+	}
 
-	if err := s.DB.Save(&Item).Error; err != nil {
+	result := s.DB.Model(&models.Item{}).Scopes(scopePendingCartItem(s.DB, user.ID, item.ID)).Update("amount", req.Amount)
+	if result.Error != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
 			Message: "update product from cart failed",
-			Error:   err,
+			Error:   result.Error,
+		})
+	}
+
+	// the cart was checked out after the ownership check , its stock is no longer held by the cart
+	if result.RowsAffected == 0 {
+		releaseStock(c.Context(), s.Redis, phone.ID, user.ID)
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "item can no longer be changed",
+			Error:   nil,
 		})
 	}
 
@@ -159,3 +259,13 @@ func (s *CartServiceImpl) UpdateItemFromCart(c *fiber.Ctx) error {
 		Data:    nil,
 	})
 }
+
+// scopePendingCartItem limits a write to the item while it is still in the user's pending cart,
+// so a checkout committed after the ownership check leaves nothing to change.
+func scopePendingCartItem(db *gorm.DB, userID uint, itemID uint) func(db *gorm.DB) *gorm.DB {
+	pendingCartIDs := db.Model(&models.Cart{}).Select("id").Where("user_id = ? AND status = ?", userID, "PENDING")
+
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Where("id = ? AND cart_id IN (?)", itemID, pendingCartIDs)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GrossMargin is the margin of one phone over the sold items , items sold before the phone had a cost price
+// are counted in UncostedQuantity and left out of Cost , CostedRevenue and Margin.
+type GrossMargin struct {
+	PhoneID          uint         `json:"phone_id"`
+	BrandName        string       `json:"brand_name"`
+	ModelName        string       `json:"model_name"`
+	Quantity         int          `json:"quantity"`
+	Revenue          models.Money `json:"revenue"`
+	CostedRevenue    models.Money `json:"costed_revenue"`
+	Cost             models.Money `json:"cost"`
+	Margin           models.Money `json:"margin"`
+	MarginPercent    float64      `json:"margin_percent"`
+	UncostedQuantity int          `json:"uncosted_quantity"`
+}
+
+// grossMarginRow is one phone sold under one VAT setting , the setting is kept so VAT can be taken out of
+// the revenue before it is set against cost.
+type grossMarginRow struct {
+	GrossMargin
+	VATRate      models.Rate
+	VATInclusive bool
+}
+
+// GetGrossMargin reports revenue before VAT against the cost captured at checkout per phone between
+// start_date and end_date (YYYY-MM-DD , the last 30 days by default). Cancelled and refunded orders are left out.
+func (s *OrderServiceImpl) GetGrossMargin(c *fiber.Ctx) error {
+	startDate := utils.GetStartOfDay().Add(-30 * 24 * time.Hour)
+	endDate := utils.GetEndOfDay()
+
+	if value := c.Query("start_date"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "start_date must be YYYY-MM-DD",
+				Error:   err,
+			})
+		}
+		startDate = date
+	}
+
+	if value := c.Query("end_date"); value != "" {
+		date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "end_date must be YYYY-MM-DD",
+				Error:   err,
+			})
+		}
+		endDate = date.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	var rows []grossMarginRow
+	if err := s.DB.Model(&models.Item{}).
+		Select("items.phone_id, MAX(items.brand_name) AS brand_name, MAX(items.model_name) AS model_name, "+
+			"orders.vat_rate, orders.vat_inclusive, "+
+			"SUM(items.amount) AS quantity, "+
+			"SUM(items.amount * items.unit_price) AS revenue, "+
+			"SUM(CASE WHEN items.unit_cost > 0 THEN items.amount * items.unit_price ELSE 0 END) AS costed_revenue, "+
+			"SUM(items.amount * items.unit_cost) AS cost, "+
+			"SUM(CASE WHEN items.unit_cost > 0 THEN 0 ELSE items.amount END) AS uncosted_quantity").
+		Joins("JOIN carts ON items.cart_id = carts.id").
+		Joins("JOIN orders ON orders.cart_id = carts.id").
+		Where("orders.created_at >= ? AND orders.created_at <= ?", startDate, endDate).
+		Where("carts.status = ?", "CONFIRMED").
+		Where("orders.status NOT IN ?", []string{models.OrderStatusCancelled, models.OrderStatusRefunded}).
+		Group("items.phone_id, orders.vat_rate, orders.vat_inclusive").
+		Order("items.phone_id ASC").
+		Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get gross margin error",
+			Error:   err,
+		})
+	}
+
+	// unit_price carries VAT when the order was VAT inclusive , take it out so margin is not overstated by the VAT
+	var margins []GrossMargin
+	for _, row := range rows {
+		row.Revenue, _, _ = utils.CalculateVAT(row.Revenue, row.VATRate, row.VATInclusive)
+		row.CostedRevenue, _, _ = utils.CalculateVAT(row.CostedRevenue, row.VATRate, row.VATInclusive)
+
+		last := len(margins) - 1
+		if last < 0 || margins[last].PhoneID != row.PhoneID {
+			margins = append(margins, row.GrossMargin)
+			continue
+		}
+
+		margins[last].Quantity += row.Quantity
+		margins[last].Revenue += row.Revenue
+		margins[last].CostedRevenue += row.CostedRevenue
+		margins[last].Cost += row.Cost
+		margins[last].UncostedQuantity += row.UncostedQuantity
+	}
+
+	var total GrossMargin
+	for i := range margins {
+		margins[i].Margin = margins[i].CostedRevenue - margins[i].Cost
+		margins[i].MarginPercent = marginPercent(margins[i].Margin, margins[i].CostedRevenue)
+
+		total.Quantity += margins[i].Quantity
+		total.Revenue += margins[i].Revenue
+		total.CostedRevenue += margins[i].CostedRevenue
+		total.Cost += margins[i].Cost
+		total.Margin += margins[i].Margin
+		total.UncostedQuantity += margins[i].UncostedQuantity
+	}
+	total.MarginPercent = marginPercent(total.Margin, total.CostedRevenue)
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get gross margin success",
+		Data: fiber.Map{
+			"start_date": startDate,
+			"end_date":   endDate,
+			"phones":     margins,
+			"total":      total,
+		},
+	})
+}
+
+func marginPercent(margin models.Money, revenue models.Money) float64 {
+	if revenue == 0 {
+		return 0
+	}
+
+	return math.Round(float64(margin)/float64(revenue)*10000) / 100
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+// lowStockChecker is set by StartLowStockChecker, until then stock changes queue nothing.
+var lowStockChecker *LowStockChecker
+
+// LowStockChecker compares phones with their reorder point in the background so checkout never waits on a notifier.
+type LowStockChecker struct {
+	DB        *gorm.DB
+	Notifiers []Notifier
+	queue     chan uint
+}
+
+// StartLowStockChecker starts the worker and queues every phone once so shortages from before the start are caught.
+func StartLowStockChecker(configClients configs.ConfigClients) {
+	checker := &LowStockChecker{
+		DB:        configClients.DB,
+		Notifiers: NewNotifiers(),
+		queue:     make(chan uint, 256),
+	}
+	lowStockChecker = checker
+
+	go checker.run()
+
+	go func() {
+		var phoneIDs []uint
+		if err := checker.DB.Model(&models.Phone{}).Where("reorder_point > 0 OR id IN (?)", checker.DB.Model(&models.LowStockAlert{}).Select("phone_id").Where("status = ?", models.LowStockAlertStatusOpen)).Pluck("id", &phoneIDs).Error; err != nil {
+			log.Printf("low stock sweep failed : %v", err)
+			return
+		}
+
+		for _, phoneID := range phoneIDs {
+			checker.queue <- phoneID
+		}
+	}()
+}
+
+// queueLowStockCheck asks the checker to look at the phones, it must be called after the change is committed.
+// A full queue drops the request, the next change to the phone queues it again.
+func queueLowStockCheck(phoneIDs ...uint) {
+	if lowStockChecker == nil {
+		return
+	}
+
+	for _, phoneID := range phoneIDs {
+		select {
+		case lowStockChecker.queue <- phoneID:
+		default:
+			log.Printf("low stock queue full , phone %d not checked", phoneID)
+		}
+	}
+}
+
+func (checker *LowStockChecker) run() {
+	for phoneID := range checker.queue {
+		if err := checker.check(phoneID); err != nil {
+			log.Printf("low stock check of phone %d failed : %v", phoneID, err)
+		}
+	}
+}
+
+// check raises an alert when the phone is below its reorder point and has none open, and resolves the open one
+// once the phone is back up. Only the single worker runs it so two alerts are never opened for one shortage.
+func (checker *LowStockChecker) check(phoneID uint) error {
+	var phone models.Phone
+	if err := checker.DB.Model(&models.Phone{}).Omit("image").Where("id = ?", phoneID).First(&phone).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var openAlert models.LowStockAlert
+	err := checker.DB.Where("phone_id = ? AND status = ?", phone.ID, models.LowStockAlertStatusOpen).First(&openAlert).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	hasOpenAlert := err == nil
+
+	isLow := phone.ReorderPoint > 0 && phone.Amount < phone.ReorderPoint
+
+	if !isLow {
+		if !hasOpenAlert {
+			return nil
+		}
+
+		now := time.Now()
+		return checker.DB.Model(&models.LowStockAlert{}).Where("id = ?", openAlert.ID).Updates(map[string]interface{}{
+			"status":      models.LowStockAlertStatusResolved,
+			"resolved_at": now,
+		}).Error
+	}
+
+	if hasOpenAlert {
+		return nil
+	}
+
+	alert := models.LowStockAlert{
+		PhoneID:      phone.ID,
+		Amount:       phone.Amount,
+		ReorderPoint: phone.ReorderPoint,
+		Status:       models.LowStockAlertStatusOpen,
+	}
+
+	if err := checker.DB.Create(&alert).Error; err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	notifyAll(ctx, checker.Notifiers,
+		fmt.Sprintf("Low stock : %s %s", phone.BrandName, phone.ModelName),
+		fmt.Sprintf("phone %d %s %s has %d left , reorder point is %d", phone.ID, phone.BrandName, phone.ModelName, phone.Amount, phone.ReorderPoint),
+	)
+
+	return nil
+}
+
+// GetLowStockPhones lists phones below their reorder point , the furthest below first.
+func (s *PhoneServiceImpl) GetLowStockPhones(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	queryPhones := s.DB.Model(&models.Phone{}).Where("reorder_point > 0 AND amount < reorder_point")
+
+	var total int64
+	if err := queryPhones.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count phones error",
+			Error:   err,
+		})
+	}
+
+	var phones []models.Phone
+	if err := queryPhones.Omit("image").Order("amount - reorder_point ASC").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&phones).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phones error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
+		Message: "get low stock phones success",
+		Data:    phones,
+		Total:   int(total),
+	})
+}
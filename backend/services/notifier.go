@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+)
+
+// Notifier is implemented by every channel the shop staff can be told about something through.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, subject string, message string) error
+}
+
+// NewNotifiers builds the notifiers listed in NOTIFIERS , comma separated from log , email and webhook.
+// Only log is used when nothing is set.
+func NewNotifiers() []Notifier {
+	names := os.Getenv("NOTIFIERS")
+	if names == "" {
+		names = "log"
+	}
+
+	notifiers := make([]Notifier, 0, 3)
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "log":
+			notifiers = append(notifiers, NewLogNotifier())
+		case "email":
+			notifiers = append(notifiers, NewEmailNotifier(
+				os.Getenv("SMTP_ADDR"),
+				os.Getenv("SMTP_USERNAME"),
+				os.Getenv("SMTP_PASSWORD"),
+				os.Getenv("NOTIFY_EMAIL_FROM"),
+				strings.Split(os.Getenv("NOTIFY_EMAIL_TO"), ","),
+			))
+		case "webhook":
+			notifiers = append(notifiers, NewWebhookNotifier(os.Getenv("NOTIFY_WEBHOOK_URL"), os.Getenv("NOTIFY_WEBHOOK_TOKEN")))
+		case "":
+		default:
+			log.Printf("unknown notifier %s , skipped", name)
+		}
+	}
+
+	return notifiers
+}
+
+// notifyAll sends through every notifier, a failing channel is logged and does not stop the others.
+func notifyAll(ctx context.Context, notifiers []Notifier, subject string, message string) {
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, subject, message); err != nil {
+			log.Printf("notifier %s failed : %v", notifier.Name(), err)
+		}
+	}
+}
+
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, subject string, message string) error {
+	log.Printf("[%s] %s", subject, message)
+	return nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// EmailNotifier sends plain text mail through an SMTP relay, usually the local one on localhost:25.
+type EmailNotifier struct {
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func NewEmailNotifier(addr string, username string, password string, from string, to []string) *EmailNotifier {
+	if addr == "" {
+		addr = "localhost:25"
+	}
+
+	recipients := make([]string, 0, len(to))
+	for _, recipient := range to {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	return &EmailNotifier{
+		addr:     addr,
+		username: username,
+		password: password,
+		from:     from,
+		to:       recipients,
+	}
+}
+
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, subject string, message string) error {
+	if n.from == "" || len(n.to) == 0 {
+		return errors.New("NOTIFY_EMAIL_FROM and NOTIFY_EMAIL_TO are required")
+	}
+
+	// a local relay takes mail without auth
+	var auth smtp.Auth
+	if n.username != "" {
+		host, _, err := net.SplitHostPort(n.addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.username, n.password, host)
+	}
+
+	// headers are ascii only , thai model names in the subject are sent as an encoded word
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		n.from, strings.Join(n.to, ", "), mime.QEncoding.Encode("utf-8", subject), message)
+
+	return smtp.SendMail(n.addr, auth, n.from, n.to, []byte(body))
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookNotifier posts the message as a form field "message" with a bearer token
+// to the chat bridge at NOTIFY_WEBHOOK_URL, there is no default endpoint.
+type WebhookNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewWebhookNotifier(webhookURL string, token string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    webhookURL,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, subject string, message string) error {
+	if n.url == "" || n.token == "" {
+		return errors.New("NOTIFY_WEBHOOK_URL and NOTIFY_WEBHOOK_TOKEN are required")
+	}
+
+	form := url.Values{"message": {fmt.Sprintf("\n%s\n%s", subject, message)}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+n.token)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notify webhook answered %s", resp.Status)
+	}
+
+	return nil
+}
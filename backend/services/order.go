@@ -1,37 +1,67 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/BaimhonS/kab-phone/configs"
 	"github.com/BaimhonS/kab-phone/models"
 	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderStatusTransition = errors.New("order status transition not allowed")
+	ErrTrackingNumberInUse   = errors.New("tracking number already used by another order")
 )
 
 type OrderServiceImpl struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Redis    *redis.Client
+	Carriers map[string]Carrier
 }
 
 type OrderService interface {
 	ConfirmOrder(c *fiber.Ctx) error
 	GetOrderByTrackingNumber(c *fiber.Ctx) error
+	GetOrderByOrderNumber(c *fiber.Ctx) error
+	GetPublicOrderTracking(c *fiber.Ctx) error
 	GetTrackingNumbers(c *fiber.Ctx) error
 	GetBestAndWorstSellingPhones(c *fiber.Ctx) error
 	GetTotalIncome(c *fiber.Ctx) error
+	GetGrossMargin(c *fiber.Ctx) error
 	GetAllOrders(c *fiber.Ctx) error
 	AddTrackingNumber(c *fiber.Ctx) error
+	UpdateOrderStatus(c *fiber.Ctx) error
+	GetOrderStatusHistories(c *fiber.Ctx) error
+	CancelOrder(c *fiber.Ctx) error
+	GetOrderReceiptPDF(c *fiber.Ctx) error
+	ImportTrackingNumbers(c *fiber.Ctx) error
 }
 
 func NewOrderService(configClients configs.ConfigClients) OrderService {
 	return &OrderServiceImpl{
-		DB: configClients.DB,
+		DB:       configClients.DB,
+		Redis:    configClients.Redis,
+		Carriers: NewCarriers(),
 	}
 }
 
 func (s *OrderServiceImpl) ConfirmOrder(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestConfirmOrder)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
 	user, ok := c.Locals("user").(models.User)
 	if !ok {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
@@ -39,19 +69,82 @@ func (s *OrderServiceImpl) ConfirmOrder(c *fiber.Ctx) error {
 		})
 	}
 
+	tx := s.DB.Begin()
+
 	var cart models.Cart
-	if err := s.DB.Model(&models.Cart{}).Where("user_id = ? AND status = ?", user.ID, "PENDING").Preload("Items").Preload("Items.Phone").First(&cart).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Cart{}).Where("user_id = ? AND status = ?", user.ID, "PENDING").Preload("Items").First(&cart).Error; err != nil {
+		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
 			Message: "cart not found",
 			Error:   err,
 		})
 	}
 
-	cart.Status = "CONFIRMED"
+	if len(cart.Items) == 0 {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "cart is empty",
+			Error:   nil,
+		})
+	}
 
-	tx := s.DB.Begin()
+	phoneIDs := make([]uint, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		phoneIDs = append(phoneIDs, item.PhoneID)
+	}
+
+	// lock phones in id order so concurrent checkouts cannot deadlock each other
+	var phones []models.Phone
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Omit("image").Where("id IN ?", phoneIDs).Order("id ASC").Find(&phones).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get phones failed",
+			Error:   err,
+		})
+	}
+
+	phoneByID := make(map[uint]models.Phone, len(phones))
+	for _, phone := range phones {
+		phoneByID[phone.ID] = phone
+	}
+
+	// phones held in other buyers' carts are not for sale to this buyer
+	reservedByOthers, err := getReservedStock(c.Context(), s.Redis, phoneIDs, user.ID)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get reserved stock failed",
+			Error:   err,
+		})
+	}
+
+	var outOfStockErrors []*utils.OutOfStockError
+	for _, item := range cart.Items {
+		phone, ok := phoneByID[item.PhoneID]
+		available := max(phone.Amount-reservedByOthers[item.PhoneID], 0)
+		if !ok || item.Amount <= 0 || available < item.Amount {
+			outOfStockErrors = append(outOfStockErrors, &utils.OutOfStockError{
+				ItemID:    item.ID,
+				PhoneID:   item.PhoneID,
+				BrandName: phone.BrandName,
+				ModelName: phone.ModelName,
+				Amount:    item.Amount,
+				Available: available,
+			})
+		}
+	}
 
-	if err := tx.Save(&cart).Error; err != nil {
+	if len(outOfStockErrors) > 0 {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.OutOfStockErrorResponse{
+			Message: "some phones in cart are out of stock",
+			Error:   outOfStockErrors,
+		})
+	}
+
+	cart.Status = "CONFIRMED"
+
+	if err := tx.Model(&models.Cart{}).Where("id = ?", cart.ID).Update("status", cart.Status).Error; err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
 			Message: "update cart failed",
@@ -59,25 +152,71 @@ func (s *OrderServiceImpl) ConfirmOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	var totalPrice float32
+	var totalPrice models.Money
 	for _, item := range cart.Items {
-		totalPrice += item.Phone.Price * float32(item.Amount)
+		phone := phoneByID[item.PhoneID]
+		totalPrice += phone.Price.Mul(item.Amount)
 
-		if err := tx.Model(&models.Phone{}).Where("id = ?", item.Phone.ID).Select("Amount").Updates(models.Phone{
-			Amount: item.Phone.Amount - item.Amount,
+		if err := tx.Model(&models.Item{}).Where("id = ?", item.ID).Updates(models.Item{
+			UnitPrice: phone.Price,
+			UnitCost:  phone.CostPrice,
+			BrandName: phone.BrandName,
+			ModelName: phone.ModelName,
+			OS:        phone.OS,
 		}).Error; err != nil {
 			tx.Rollback()
 			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
-				Message: "update phone amount failed",
+				Message: "update item snapshot failed",
 				Error:   err,
 			})
 		}
 	}
 
+	var carrier models.ShippingCarrier
+	if err := tx.Model(&models.ShippingCarrier{}).Where("code = ? AND active = ?", req.ShippingCarrier, true).Preload("Rates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("max_weight ASC")
+	}).First(&carrier).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "shipping carrier not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get shipping carrier error",
+			Error:   err,
+		})
+	}
+
+	quote, err := quoteShipping(carrier, utils.GetShippingZone(req.Province), cartShippingWeight(cart.Items, phoneByID), totalPrice)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: err.Error(),
+			Error:   err,
+		})
+	}
+
+	orderNumber, err := generateUniqueOrderNumber(tx)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "generate order number failed",
+			Error:   err,
+		})
+	}
+
 	var order models.Order
 	order.CartID = cart.ID
-	order.TrackingNumber = "wait for tracking number"
-	order.TotalPrice = totalPrice
+	order.OrderNumber = orderNumber
+	order.ShippingCarrier = quote.CarrierCode
+	order.ShippingZone = quote.Zone
+	order.ShippingWeight = quote.Weight
+	order.ShippingFee = quote.Fee
+	order.VATRate, order.VATInclusive = utils.GetVATConfig()
+	order.Subtotal, order.VATAmount, order.TotalPrice = utils.CalculateVAT(totalPrice+order.ShippingFee, order.VATRate, order.VATInclusive)
+	order.Status = models.OrderStatusAwaitingPayment
 
 	if err := tx.Save(&order).Error; err != nil {
 		tx.Rollback()
@@ -87,6 +226,41 @@ func (s *OrderServiceImpl) ConfirmOrder(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := tx.Create(&models.OrderStatusHistory{
+		OrderID:     order.ID,
+		ToStatus:    order.Status,
+		ChangedByID: &user.ID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "create order status history failed",
+			Error:   err,
+		})
+	}
+
+	// stock is taken once the order exists so every sale movement names the order that took it
+	for _, item := range cart.Items {
+		if err := adjustPhoneStock(tx, &models.StockMovement{
+			PhoneID:     item.PhoneID,
+			Type:        models.StockMovementSale,
+			Quantity:    -item.Amount,
+			OrderID:     &order.ID,
+			CreatedByID: userIDOrNil(user.ID),
+		}); err != nil {
+			tx.Rollback()
+			if err == ErrInsufficientStock {
+				return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+					Message: "phone amount changed during checkout, please try again",
+					Error:   nil,
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "update phone amount failed",
+				Error:   err,
+			})
+		}
+	}
+
 	if err := tx.Create(&models.Cart{
 		UserId: user.ID,
 		Status: "PENDING",
@@ -100,9 +274,16 @@ func (s *OrderServiceImpl) ConfirmOrder(c *fiber.Ctx) error {
 
 	tx.Commit()
 
+	// the stock is taken now, a hold left behind would only expire on its own
+	for _, phoneID := range phoneIDs {
+		releaseStock(c.Context(), s.Redis, phoneID, user.ID)
+	}
+
+	queueLowStockCheck(phoneIDs...)
+
 	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
 		Message: "confirm order success",
-		Data:    nil,
+		Data:    order,
 	})
 }
 
@@ -122,20 +303,25 @@ func (s *OrderServiceImpl) GetAllOrders(c *fiber.Ctx) error {
 }
 
 func (s *OrderServiceImpl) AddTrackingNumber(c *fiber.Ctx) error {
-	var request struct {
-		OrderID        uint   `json:"order_id"`
-		TrackingNumber string `json:"tracking_number"`
+	req, ok := c.Locals("req").(validates.RequestAddTrackingNumber)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
 	}
 
-	if err := c.BodyParser(&request); err != nil {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
-			Message: "invalid request body",
-			Error:   err,
+			Message: "local user not found",
 		})
 	}
 
+	tx := s.DB.Begin()
+
 	var order models.Order
-	if err := s.DB.Model(&models.Order{}).Where("id = ?", request.OrderID).First(&order).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", req.OrderID).First(&order).Error; err != nil {
+		tx.Rollback()
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
 				Message: "order not found",
@@ -148,24 +334,322 @@ func (s *OrderServiceImpl) AddTrackingNumber(c *fiber.Ctx) error {
 		})
 	}
 
-	order.TrackingNumber = request.TrackingNumber
-
-	if err := s.DB.Save(&order).Error; err != nil {
+	if err := shipOrder(tx, s.Carriers, &order, req.TrackingNumber, req.Carrier, user.ID); err != nil {
+		tx.Rollback()
+		switch err {
+		case ErrOrderStatusTransition:
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: "order must be packing before it can be shipped",
+				Error:   err,
+			})
+		case ErrTrackingNumberInvalid:
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("tracking number is not a valid %s tracking number", order.ShippingCarrier),
+				Error:   err,
+			})
+		case ErrTrackingNumberInUse:
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: err.Error(),
+				Error:   err,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
 			Message: "update tracking number failed",
 			Error:   err,
 		})
 	}
 
+	tx.Commit()
+
 	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
 		Message: "tracking number added successfully",
 		Data:    order,
 	})
 }
 
+func (s *OrderServiceImpl) UpdateOrderStatus(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestUpdateOrderStatus)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).First(&order).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if err := changeOrderStatus(tx, &order, req.Status, user.ID, req.Note); err != nil {
+		tx.Rollback()
+		if err == ErrOrderStatusTransition {
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("cannot change order status from %s to %s", order.Status, req.Status),
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update order status failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "update order status success",
+		Data:    order,
+	})
+}
+
+func (s *OrderServiceImpl) GetOrderStatusHistories(c *fiber.Ctx) error {
+	var histories []models.OrderStatusHistory
+	if err := s.DB.Model(&models.OrderStatusHistory{}).Where("order_id = ?", c.Params("id")).Preload("ChangedBy", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "first_name", "last_name", "role")
+	}).Order("created_at ASC, id ASC").Find(&histories).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get order status histories error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get order status histories success",
+		Data:    histories,
+	})
+}
+
+func (s *OrderServiceImpl) CancelOrder(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestCancelOrder)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).Preload("Cart").Preload("Cart.Items").First(&order).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if !canViewOrder(user, order) {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	if !isAdmin(user) {
+		if order.Status == models.OrderStatusShipped {
+			tx.Rollback()
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: "order already shipped, please contact the shop to cancel",
+				Error:   nil,
+			})
+		}
+	}
+
+	// legacy orders never recorded their sale in the ledger , putting their stock back would count it twice
+	restock := order.Status != models.OrderStatusLegacy
+
+	if err := changeOrderStatus(tx, &order, models.OrderStatusCancelled, user.ID, req.Reason); err != nil {
+		tx.Rollback()
+		if err == ErrOrderStatusTransition {
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("cannot cancel order with status %s", order.Status),
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update order status failed",
+			Error:   err,
+		})
+	}
+
+	if restock {
+		if err := restoreOrderStock(tx, &order, user.ID, req.Reason); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "restore phone amount failed",
+				Error:   err,
+			})
+		}
+	}
+
+	order.CancelReason = req.Reason
+
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("cancel_reason", order.CancelReason).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update cancel reason failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	queueLowStockCheck(orderPhoneIDs(order)...)
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "cancel order success",
+		Data:    order,
+	})
+}
+
+// changeOrderStatus moves the order to status and records who made the change,
+// a changedByID of 0 records the change as made by the system.
+// The caller is expected to hold a row lock on the order inside tx.
+func changeOrderStatus(tx *gorm.DB, order *models.Order, status string, changedByID uint, note string) error {
+	if !order.CanTransitionTo(status) {
+		return ErrOrderStatusTransition
+	}
+
+	history := models.OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   status,
+		Note:       note,
+	}
+
+	if changedByID != 0 {
+		history.ChangedByID = &changedByID
+	}
+
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("status", status).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	if err := syncOrderUnits(tx, order.ID, status); err != nil {
+		return err
+	}
+
+	switch status {
+	case models.OrderStatusDelivered:
+		if err := createOrderWarranties(tx, order); err != nil {
+			return err
+		}
+	case models.OrderStatusRefunded:
+		if err := endOrderWarranties(tx, order.ID); err != nil {
+			return err
+		}
+	}
+
+	order.Status = status
+
+	return nil
+}
+
+// generateUniqueOrderNumber retries on the rare collision, the unique index still guards concurrent checkouts.
+func generateUniqueOrderNumber(tx *gorm.DB) (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		orderNumber, err := utils.GenerateOrderNumber(time.Now().Year())
+		if err != nil {
+			return "", err
+		}
+
+		var count int64
+		if err := tx.Model(&models.Order{}).Unscoped().Where("order_number = ?", orderNumber).Count(&count).Error; err != nil {
+			return "", err
+		}
+
+		if count == 0 {
+			return orderNumber, nil
+		}
+	}
+
+	return "", errors.New("could not generate a unique order number")
+}
+
+// shipOrder moves a packing order to shipped under trackingNumber, carrierCode overrides the
+// carrier chosen at checkout when given. The caller is expected to hold a row lock on the order.
+func shipOrder(tx *gorm.DB, carriers map[string]Carrier, order *models.Order, trackingNumber string, carrierCode string, changedByID uint) error {
+	if carrierCode != "" {
+		order.ShippingCarrier = carrierCode
+	}
+
+	// carriers without an adapter, e.g. a walk-in messenger, keep free form tracking numbers
+	if carrier, ok := carriers[order.ShippingCarrier]; ok && !carrier.ValidateTrackingNumber(trackingNumber) {
+		return ErrTrackingNumberInvalid
+	}
+
+	var count int64
+	if err := tx.Model(&models.Order{}).Where("tracking_number = ? AND id <> ?", trackingNumber, order.ID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return ErrTrackingNumberInUse
+	}
+
+	if err := changeOrderStatus(tx, order, models.OrderStatusShipped, changedByID, trackingNumber); err != nil {
+		return err
+	}
+
+	order.TrackingNumber = trackingNumber
+
+	return tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+		"tracking_number":  order.TrackingNumber,
+		"shipping_carrier": order.ShippingCarrier,
+	}).Error
+}
+
 func (s *OrderServiceImpl) GetOrderByTrackingNumber(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
 	var order models.Order
-	if err := s.DB.Model(&models.Order{}).Where("tracking_number = ?", c.Params("tracking_number")).Preload("Cart").Preload("Cart.Items").Preload("Cart.Items.Phone").First(&order).Error; err != nil {
+	if err := s.DB.Model(&models.Order{}).Where("tracking_number = ?", c.Params("tracking_number")).Preload("Cart").Preload("Cart.Items").Preload("Cart.Items.Phone").Preload("PaymentSlips", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("image")
+	}).Preload("ShipmentEvents", func(db *gorm.DB) *gorm.DB {
+		return db.Order("occurred_at ASC, id ASC")
+	}).First(&order).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
 				Message: "order not found",
@@ -179,12 +663,67 @@ func (s *OrderServiceImpl) GetOrderByTrackingNumber(c *fiber.Ctx) error {
 		})
 	}
 
+	if !canViewOrder(user, order) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
 		Message: "get order by tracking number success",
 		Data:    order,
 	})
 }
 
+func (s *OrderServiceImpl) GetOrderByOrderNumber(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	orderNumber := strings.ToUpper(c.Params("order_number"))
+	if !utils.ValidateOrderNumber(orderNumber) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "order number invalid",
+			Error:   nil,
+		})
+	}
+
+	var order models.Order
+	if err := s.DB.Model(&models.Order{}).Where("order_number = ?", orderNumber).Preload("Cart").Preload("Cart.Items").Preload("PaymentSlips", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("image")
+	}).Preload("ShipmentEvents", func(db *gorm.DB) *gorm.DB {
+		return db.Order("occurred_at ASC, id ASC")
+	}).First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get order by order number error",
+			Error:   err,
+		})
+	}
+
+	if !canViewOrder(user, order) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get order by order number success",
+		Data:    order,
+	})
+}
+
 func (s *OrderServiceImpl) GetTrackingNumbers(c *fiber.Ctx) error {
 	user, ok := c.Locals("user").(models.User)
 	if !ok {
@@ -204,7 +743,7 @@ func (s *OrderServiceImpl) GetTrackingNumbers(c *fiber.Ctx) error {
 	queryOrders := s.DB.Model(&models.Order{}).Joins("JOIN carts ON orders.cart_id = carts.id").Where("carts.user_id = ?", user.ID)
 
 	if query.Search != "" {
-		queryOrders.Where("tracking_number LIKE ?", "%"+query.Search+"%")
+		queryOrders.Where("(orders.tracking_number LIKE ? OR orders.order_number LIKE ?)", "%"+query.Search+"%", "%"+query.Search+"%")
 	}
 
 	var orders []models.Order
@@ -336,12 +875,11 @@ func (s *OrderServiceImpl) GetBestAndWorstSellingPhones(c *fiber.Ctx) error {
 
 func queryGetBestPhone(startDate time.Time, endDate time.Time, phone *models.Phone, db *gorm.DB) error {
 	return db.Model(&models.Item{}).
-		Select("SUM(items.amount) as amount_sell, phones.*").
+		Select("SUM(items.amount) as amount_sell, items.phone_id as id, MAX(items.brand_name) as brand_name, MAX(items.model_name) as model_name, MAX(items.os) as os").
 		Joins("JOIN carts ON items.cart_id = carts.id").
-		Joins("JOIN phones ON phones.id = items.phone_id").
 		Joins("JOIN orders ON orders.cart_id = carts.id").
 		Where("orders.created_at >= ? AND orders.created_at <= ?", startDate, endDate).
-		Group("phones.id").
+		Group("items.phone_id").
 		Order("amount_sell DESC").
 		Limit(1).
 		Scan(&phone).Error
@@ -349,12 +887,11 @@ func queryGetBestPhone(startDate time.Time, endDate time.Time, phone *models.Pho
 
 func queryGetWorstPhone(startDate time.Time, endDate time.Time, phone *models.Phone, db *gorm.DB) error {
 	return db.Model(&models.Item{}).
-		Select("SUM(items.amount) as amount_sell, phones.*").
+		Select("SUM(items.amount) as amount_sell, items.phone_id as id, MAX(items.brand_name) as brand_name, MAX(items.model_name) as model_name, MAX(items.os) as os").
 		Joins("JOIN carts ON items.cart_id = carts.id").
-		Joins("JOIN phones ON phones.id = items.phone_id").
 		Joins("JOIN orders ON orders.cart_id = carts.id").
 		Where("orders.created_at >= ? AND orders.created_at <= ?", startDate, endDate).
-		Group("phones.id").
+		Group("items.phone_id").
 		Order("amount_sell ASC").
 		Limit(1).
 		Scan(&phone).Error
@@ -362,8 +899,8 @@ func queryGetWorstPhone(startDate time.Time, endDate time.Time, phone *models.Ph
 
 type TotalIncome struct {
 	CreatedAt time.Time
-	Amount    float64
-	Price     float64
+	Amount    int
+	Price     models.Money
 }
 
 func (s *OrderServiceImpl) GetTotalIncome(c *fiber.Ctx) error {
@@ -404,25 +941,63 @@ func (s *OrderServiceImpl) GetTotalIncome(c *fiber.Ctx) error {
 		})
 	}
 
-	totalIncomeDayAmount := 0.0
-	totalIncomeWeekAmount := 0.0
-	totalIncomeMonthAmount := 0.0
-	totalIncomeYearAmount := 0.0
+	var totalRefundDay models.Money
+	var totalRefundWeek models.Money
+	var totalRefundMonth models.Money
+	var totalRefundYear models.Money
+
+	// get total refund of the day
+	if err := queryGetTotalRefund(utils.GetStartOfDay(), utils.GetEndOfDay(), &totalRefundDay, s.DB); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get total refund of the day error",
+			Error:   err,
+		})
+	}
+
+	// get total refund of the week
+	if err := queryGetTotalRefund(utils.GetStartOfDay().Add(-7*24*time.Hour), utils.GetEndOfDay(), &totalRefundWeek, s.DB); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get total refund of the week error",
+			Error:   err,
+		})
+	}
+
+	// get total refund of the month
+	if err := queryGetTotalRefund(utils.GetStartOfDay().Add(-30*24*time.Hour), utils.GetEndOfDay(), &totalRefundMonth, s.DB); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get total refund of the month error",
+			Error:   err,
+		})
+	}
+
+	// get total refund of the year
+	if err := queryGetTotalRefund(utils.GetStartOfDay().Add(-365*24*time.Hour), utils.GetEndOfDay(), &totalRefundYear, s.DB); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get total refund of the year error",
+			Error:   err,
+		})
+	}
+
+	// refunds count against the period they were paid out in
+	totalIncomeDayAmount := -totalRefundDay
+	totalIncomeWeekAmount := -totalRefundWeek
+	totalIncomeMonthAmount := -totalRefundMonth
+	totalIncomeYearAmount := -totalRefundYear
 
 	for _, income := range totalIncomeDay {
-		totalIncomeDayAmount += income.Amount * income.Price
+		totalIncomeDayAmount += income.Price.Mul(income.Amount)
 	}
 
 	for _, income := range totalIncomeWeek {
-		totalIncomeWeekAmount += income.Amount * income.Price
+		totalIncomeWeekAmount += income.Price.Mul(income.Amount)
 	}
 
 	for _, income := range totalIncomeMonth {
-		totalIncomeMonthAmount += income.Amount * income.Price
+		totalIncomeMonthAmount += income.Price.Mul(income.Amount)
 	}
 
 	for _, income := range totalIncomeYear {
-		totalIncomeYearAmount += income.Amount * income.Price
+		totalIncomeYearAmount += income.Price.Mul(income.Amount)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
@@ -432,17 +1007,73 @@ func (s *OrderServiceImpl) GetTotalIncome(c *fiber.Ctx) error {
 			"total_income_week":  totalIncomeWeekAmount,
 			"total_income_month": totalIncomeMonthAmount,
 			"total_income_year":  totalIncomeYearAmount,
+			"total_refund_day":   totalRefundDay,
+			"total_refund_week":  totalRefundWeek,
+			"total_refund_month": totalRefundMonth,
+			"total_refund_year":  totalRefundYear,
 		},
 	})
 }
 
 func queryGetTotalIncome(startDate time.Time, endDate time.Time, totalIncome *[]TotalIncome, db *gorm.DB) error {
 	return db.Model(&models.Item{}).
-		Select("items.amount, phones.price, orders.created_at").
+		Select("items.amount, items.unit_price as price, orders.created_at").
 		Joins("JOIN carts ON items.cart_id = carts.id").
-		Joins("JOIN phones ON phones.id = items.phone_id").
 		Joins("JOIN orders ON orders.cart_id = carts.id").
 		Where("orders.created_at >= ? AND orders.created_at <= ?", startDate, endDate).
 		Where("carts.status = ?", "CONFIRMED").
+		Where("orders.status <> ?", models.OrderStatusCancelled).
 		Find(&totalIncome).Error
 }
+
+// queryGetTotalRefund sums money paid back to customers, both completed returns and refunded payments.
+func queryGetTotalRefund(startDate time.Time, endDate time.Time, totalRefund *models.Money, db *gorm.DB) error {
+	var returnRefund models.Money
+	if err := db.Model(&models.Return{}).
+		Select("COALESCE(SUM(refund_amount), 0)").
+		Where("completed_at >= ? AND completed_at <= ?", startDate, endDate).
+		Scan(&returnRefund).Error; err != nil {
+		return err
+	}
+
+	var paymentRefund models.Money
+	if err := db.Model(&models.Payment{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("status = ?", models.PaymentStatusRefunded).
+		Where("refunded_at >= ? AND refunded_at <= ?", startDate, endDate).
+		Scan(&paymentRefund).Error; err != nil {
+		return err
+	}
+
+	*totalRefund = returnRefund + paymentRefund
+
+	return nil
+}
+
+func orderPhoneIDs(order models.Order) []uint {
+	phoneIDs := make([]uint, 0, len(order.Cart.Items))
+	for _, item := range order.Cart.Items {
+		phoneIDs = append(phoneIDs, item.PhoneID)
+	}
+
+	return phoneIDs
+}
+
+// restoreOrderStock puts every item of the order back on the shelf as cancellation movements.
+// Soft-deleted phones are restored too so the numbers add up if they are brought back.
+func restoreOrderStock(tx *gorm.DB, order *models.Order, changedByID uint, note string) error {
+	for _, item := range order.Cart.Items {
+		if err := adjustPhoneStock(tx, &models.StockMovement{
+			PhoneID:     item.PhoneID,
+			Type:        models.StockMovementCancellation,
+			Quantity:    item.Amount,
+			OrderID:     &order.ID,
+			Note:        note,
+			CreatedByID: userIDOrNil(changedByID),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
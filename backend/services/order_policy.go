@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/BaimhonS/kab-phone/models"
+
+	"gorm.io/gorm"
+)
+
+// Order policy, customers only reach their own orders and admins reach every order.
+// Callers answer 404 rather than 403 so order ids and numbers of other customers are not confirmed.
+
+func isAdmin(user models.User) bool {
+	return user.Role == "admin"
+}
+
+// canViewOrder expects order.Cart to be loaded.
+func canViewOrder(user models.User, order models.Order) bool {
+	return isAdmin(user) || order.Cart.UserId == user.ID
+}
+
+// findViewableOrder loads the order with its cart, orders the user may not view are reported as gorm.ErrRecordNotFound.
+func findViewableOrder(db *gorm.DB, user models.User, id string) (models.Order, error) {
+	var order models.Order
+	if err := db.Model(&models.Order{}).Where("id = ?", id).Preload("Cart").First(&order).Error; err != nil {
+		return models.Order{}, err
+	}
+
+	if !canViewOrder(user, order) {
+		return models.Order{}, gorm.ErrRecordNotFound
+	}
+
+	return order, nil
+}
+
+// scopeOrderOwner limits a query on a table with an order id column to the user's orders,
+// it leaves the query untouched for admins.
+func scopeOrderOwner(user models.User, orderIDColumn string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if isAdmin(user) {
+			return db
+		}
+
+		return db.Where(fmt.Sprintf("%s IN (?)", orderIDColumn), db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Order{}).
+			Select("orders.id").
+			Joins("JOIN carts ON carts.id = orders.cart_id").
+			Where("carts.user_id = ?", user.ID))
+	}
+}
@@ -0,0 +1,579 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+	"github.com/skip2/go-qrcode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentServiceImpl struct {
+	DB        *gorm.DB
+	Providers map[string]PaymentProvider
+}
+
+type PaymentService interface {
+	CreatePayment(c *fiber.Ctx) error
+	ConfirmPayment(c *fiber.Ctx) error
+	RefundPayment(c *fiber.Ctx) error
+	HandlePaymentWebhook(c *fiber.Ctx) error
+	GetOrderPromptPay(c *fiber.Ctx) error
+	ReceiveOrderPromptPay(c *fiber.Ctx) error
+}
+
+func NewPaymentService(configClients configs.ConfigClients) PaymentService {
+	return &PaymentServiceImpl{
+		DB:        configClients.DB,
+		Providers: NewPaymentProviders(),
+	}
+}
+
+func (s *PaymentServiceImpl) CreatePayment(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestCreatePayment)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	provider, ok := s.Providers[req.Provider]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "payment provider not supported",
+			Error:   nil,
+		})
+	}
+
+	var order models.Order
+	if err := s.DB.Model(&models.Order{}).Where("id = ?", req.OrderID).Preload("Cart").First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if order.Cart.UserId != user.ID {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	if order.Status != models.OrderStatusAwaitingPayment {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot pay order with status %s", order.Status),
+			Error:   nil,
+		})
+	}
+
+	payment := models.Payment{
+		OrderID:  order.ID,
+		Provider: provider.Name(),
+		Amount:   order.TotalPrice,
+		Status:   models.PaymentStatusPending,
+	}
+
+	intent, err := provider.CreateIntent(payment)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(utils.ErrorResponse{
+			Message: "create payment intent failed",
+			Error:   err,
+		})
+	}
+
+	payment.ProviderRef = intent.ProviderRef
+	payment.Payload = intent.Payload
+
+	if err := s.DB.Create(&payment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "create payment failed",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "create payment success",
+		Data:    payment,
+	})
+}
+
+// ConfirmPayment asks the provider for the result of a pending payment, admins use it to settle
+// payments whose webhook never arrived. Buyers wait for the signed webhook instead.
+func (s *PaymentServiceImpl) ConfirmPayment(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).First(&payment).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "payment not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if payment.Status != models.PaymentStatusPending {
+		tx.Rollback()
+		return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+			Message: "payment already confirmed",
+			Data:    payment,
+		})
+	}
+
+	provider, ok := s.Providers[payment.Provider]
+	if !ok {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "payment provider not supported",
+			Error:   nil,
+		})
+	}
+
+	status, err := provider.Confirm(payment)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadGateway).JSON(utils.ErrorResponse{
+			Message: "confirm payment with provider failed",
+			Error:   err,
+		})
+	}
+
+	if err := applyPaymentStatus(tx, &payment, status, user.ID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update payment status failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "confirm payment success",
+		Data:    payment,
+	})
+}
+
+func (s *PaymentServiceImpl) RefundPayment(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).First(&payment).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "payment not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if payment.Status != models.PaymentStatusSucceeded {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot refund payment with status %s", payment.Status),
+			Error:   nil,
+		})
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", payment.OrderID).Preload("Cart").Preload("Cart.Items").First(&order).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get order failed",
+			Error:   err,
+		})
+	}
+
+	// orders refunded before they leave the shop put their phones back on the shelf,
+	// cancelled orders already did and shipped orders come back through returns
+	restoreStock := order.Status == models.OrderStatusPaid || order.Status == models.OrderStatusPacking
+
+	if err := changeOrderStatus(tx, &order, models.OrderStatusRefunded, user.ID, fmt.Sprintf("refund payment %s", payment.ProviderRef)); err != nil {
+		tx.Rollback()
+		if err == ErrOrderStatusTransition {
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("cannot refund order with status %s", order.Status),
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update order status failed",
+			Error:   err,
+		})
+	}
+
+	if restoreStock {
+		if err := restoreOrderStock(tx, &order, user.ID, fmt.Sprintf("refund payment %s", payment.ProviderRef)); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "restore phone amount failed",
+				Error:   err,
+			})
+		}
+	}
+
+	provider, ok := s.Providers[payment.Provider]
+	if !ok {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "payment provider not supported",
+			Error:   nil,
+		})
+	}
+
+	if err := provider.Refund(payment); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadGateway).JSON(utils.ErrorResponse{
+			Message: "refund payment with provider failed",
+			Error:   err,
+		})
+	}
+
+	if err := applyPaymentStatus(tx, &payment, models.PaymentStatusRefunded, user.ID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update payment status failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	if restoreStock {
+		queueLowStockCheck(orderPhoneIDs(order)...)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "refund payment success",
+		Data:    payment,
+	})
+}
+
+func (s *PaymentServiceImpl) HandlePaymentWebhook(c *fiber.Ctx) error {
+	provider, ok := s.Providers[c.Query("provider")]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "payment provider not supported",
+			Error:   nil,
+		})
+	}
+
+	event, err := provider.VerifyWebhook(c.Body(), c.Get("X-Payment-Signature"))
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.ErrorResponse{
+			Message: "verify payment webhook failed",
+			Error:   err,
+		})
+	}
+
+	if event.Status != models.PaymentStatusSucceeded && event.Status != models.PaymentStatusFailed {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "payment webhook status invalid",
+			Error:   nil,
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("provider = ? AND provider_ref = ?", provider.Name(), event.ProviderRef).First(&payment).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "payment not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	// providers retry webhooks, a payment that already left pending is acknowledged as is
+	if payment.Status != models.PaymentStatusPending {
+		tx.Rollback()
+		return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+			Message: "payment webhook already handled",
+			Data:    nil,
+		})
+	}
+
+	if err := applyPaymentStatus(tx, &payment, event.Status, 0); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update payment status failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "handle payment webhook success",
+		Data:    nil,
+	})
+}
+
+func (s *PaymentServiceImpl) GetOrderPromptPay(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	var order models.Order
+	if err := s.DB.Model(&models.Order{}).Where("id = ?", c.Params("id")).Preload("Cart").First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if !canViewOrder(user, order) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	if order.Status != models.OrderStatusAwaitingPayment {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot pay order with status %s", order.Status),
+			Error:   nil,
+		})
+	}
+
+	payment, err := findOrCreatePromptPayPayment(s.DB, s.Providers["promptpay"], order)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "create promptpay payment failed",
+			Error:   err,
+		})
+	}
+
+	image, err := qrcode.Encode(payment.Payload, qrcode.Medium, 512)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "generate promptpay qr code failed",
+			Error:   err,
+		})
+	}
+
+	if c.Query("format") == "png" {
+		c.Set("Content-Type", "image/png")
+		return c.Send(image)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get order promptpay success",
+		Data: fiber.Map{
+			"payment_id": payment.ID,
+			"amount":     payment.Amount,
+			"payload":    payment.Payload,
+			"image":      image,
+		},
+	})
+}
+
+func (s *PaymentServiceImpl) ReceiveOrderPromptPay(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestReceivePromptPay)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).First(&order).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if order.Status != models.OrderStatusAwaitingPayment {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot receive payment for order with status %s", order.Status),
+			Error:   nil,
+		})
+	}
+
+	payment, err := findOrCreatePromptPayPayment(tx, s.Providers["promptpay"], order)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "create promptpay payment failed",
+			Error:   err,
+		})
+	}
+
+	payment.SlipRef = req.SlipRef
+
+	if err := tx.Model(&models.Payment{}).Where("id = ?", payment.ID).Update("slip_ref", payment.SlipRef).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update slip reference failed",
+			Error:   err,
+		})
+	}
+
+	if err := applyPaymentStatus(tx, &payment, models.PaymentStatusSucceeded, user.ID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update payment status failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "receive promptpay payment success",
+		Data:    payment,
+	})
+}
+
+// findOrCreatePromptPayPayment reuses the pending promptpay payment of the order so
+// reloading the qr code does not pile up payments.
+func findOrCreatePromptPayPayment(db *gorm.DB, provider PaymentProvider, order models.Order) (models.Payment, error) {
+	var payment models.Payment
+	err := db.Model(&models.Payment{}).Where("order_id = ? AND provider = ? AND status = ?", order.ID, provider.Name(), models.PaymentStatusPending).Order("id DESC").First(&payment).Error
+	if err == nil && payment.Amount == order.TotalPrice {
+		return payment, nil
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return payment, err
+	}
+
+	payment = models.Payment{
+		OrderID:  order.ID,
+		Provider: provider.Name(),
+		Amount:   order.TotalPrice,
+		Status:   models.PaymentStatusPending,
+	}
+
+	intent, err := provider.CreateIntent(payment)
+	if err != nil {
+		return payment, err
+	}
+
+	payment.ProviderRef = intent.ProviderRef
+	payment.Payload = intent.Payload
+
+	if err := db.Create(&payment).Error; err != nil {
+		return payment, err
+	}
+
+	return payment, nil
+}
+
+// applyPaymentStatus stores the new payment status and moves an order that was awaiting payment to paid.
+func applyPaymentStatus(tx *gorm.DB, payment *models.Payment, status string, changedByID uint) error {
+	if status == payment.Status {
+		return nil
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+
+	switch status {
+	case models.PaymentStatusSucceeded:
+		payment.PaidAt = &now
+		updates["paid_at"] = now
+	case models.PaymentStatusRefunded:
+		payment.RefundedAt = &now
+		updates["refunded_at"] = now
+	}
+
+	if err := tx.Model(&models.Payment{}).Where("id = ?", payment.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	payment.Status = status
+
+	if status != models.PaymentStatusSucceeded {
+		return nil
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", payment.OrderID).First(&order).Error; err != nil {
+		return err
+	}
+
+	// a payment landing on an order that was cancelled meanwhile is left for an admin to refund
+	if order.Status != models.OrderStatusAwaitingPayment {
+		return nil
+	}
+
+	return changeOrderStatus(tx, &order, models.OrderStatusPaid, changedByID, fmt.Sprintf("paid via %s %s", payment.Provider, payment.ProviderRef))
+}
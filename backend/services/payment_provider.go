@@ -0,0 +1,92 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+)
+
+var ErrPaymentSignatureInvalid = errors.New("payment webhook signature invalid")
+
+type (
+	PaymentIntent struct {
+		ProviderRef string
+		Payload     string
+	}
+
+	PaymentWebhookEvent struct {
+		ProviderRef string `json:"provider_ref"`
+		Status      string `json:"status"` // SUCCEEDED , FAILED
+	}
+)
+
+// PaymentProvider is implemented by every payment gateway the shop can take money through.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(payment models.Payment) (PaymentIntent, error)
+	Confirm(payment models.Payment) (string, error)
+	Refund(payment models.Payment) error
+	VerifyWebhook(body []byte, signature string) (PaymentWebhookEvent, error)
+}
+
+func NewPaymentProviders() map[string]PaymentProvider {
+	providers := []PaymentProvider{
+		NewPromptPayPaymentProvider(os.Getenv("PROMPTPAY_ID"), os.Getenv("PAYMENT_WEBHOOK_SECRET")),
+	}
+
+	// the mock approves any payment on confirm , it must never be reachable in production
+	if utils.IsDevelopment() {
+		providers = append(providers, NewMockPaymentProvider(os.Getenv("PAYMENT_WEBHOOK_SECRET")))
+	}
+
+	providerByName := make(map[string]PaymentProvider, len(providers))
+	for _, provider := range providers {
+		providerByName[provider.Name()] = provider
+	}
+
+	return providerByName
+}
+
+func generateProviderRef(prefix string) (string, error) {
+	randomBytes := make([]byte, 12)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	return prefix + "_" + hex.EncodeToString(randomBytes), nil
+}
+
+// verifyWebhookSignature checks that signature is the hex HMAC-SHA256 of body and decodes the event.
+func verifyWebhookSignature(secret string, body []byte, signature string) (PaymentWebhookEvent, error) {
+	var event PaymentWebhookEvent
+
+	if !verifyHMACSignature(secret, body, signature) {
+		return event, ErrPaymentSignatureInvalid
+	}
+
+	if err := json.Unmarshal(body, &event); err != nil {
+		return event, err
+	}
+
+	return event, nil
+}
+
+// verifyHMACSignature reports whether signature is the hex HMAC-SHA256 of body, an empty secret never verifies.
+func verifyHMACSignature(secret string, body []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
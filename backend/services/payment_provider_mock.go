@@ -0,0 +1,45 @@
+package services
+
+import (
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+// MockPaymentProvider approves every payment as soon as it is confirmed,
+// it is meant for local development and demos and is only registered when APP_ENV is development or test.
+type MockPaymentProvider struct {
+	WebhookSecret string
+}
+
+func NewMockPaymentProvider(webhookSecret string) PaymentProvider {
+	return &MockPaymentProvider{
+		WebhookSecret: webhookSecret,
+	}
+}
+
+func (p *MockPaymentProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockPaymentProvider) CreateIntent(payment models.Payment) (PaymentIntent, error) {
+	providerRef, err := generateProviderRef(p.Name())
+	if err != nil {
+		return PaymentIntent{}, err
+	}
+
+	return PaymentIntent{
+		ProviderRef: providerRef,
+		Payload:     providerRef,
+	}, nil
+}
+
+func (p *MockPaymentProvider) Confirm(payment models.Payment) (string, error) {
+	return models.PaymentStatusSucceeded, nil
+}
+
+func (p *MockPaymentProvider) Refund(payment models.Payment) error {
+	return nil
+}
+
+func (p *MockPaymentProvider) VerifyWebhook(body []byte, signature string) (PaymentWebhookEvent, error) {
+	return verifyWebhookSignature(p.WebhookSecret, body, signature)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+)
+
+// PromptPayPaymentProvider generates PromptPay QR payloads locally,
+// the transfer itself is reported back through the webhook or marked by an admin.
+type PromptPayPaymentProvider struct {
+	PromptPayID   string
+	WebhookSecret string
+}
+
+// NewPromptPayPaymentProvider refuses a malformed promptPayID up front, the provider then answers as not configured
+// instead of handing out QR codes that pay someone else.
+func NewPromptPayPaymentProvider(promptPayID string, webhookSecret string) PaymentProvider {
+	if promptPayID != "" {
+		normalized, err := utils.NormalizePromptPayID(promptPayID)
+		if err != nil {
+			log.Printf("PROMPTPAY_ID %q rejected : %v", promptPayID, err)
+		}
+		promptPayID = normalized
+	}
+
+	return &PromptPayPaymentProvider{
+		PromptPayID:   promptPayID,
+		WebhookSecret: webhookSecret,
+	}
+}
+
+func (p *PromptPayPaymentProvider) Name() string {
+	return "promptpay"
+}
+
+func (p *PromptPayPaymentProvider) CreateIntent(payment models.Payment) (PaymentIntent, error) {
+	if p.PromptPayID == "" {
+		return PaymentIntent{}, errors.New("promptpay id is not configured")
+	}
+
+	providerRef, err := generateProviderRef(p.Name())
+	if err != nil {
+		return PaymentIntent{}, err
+	}
+
+	payload, err := utils.GeneratePromptPayPayload(p.PromptPayID, payment.Amount)
+	if err != nil {
+		return PaymentIntent{}, err
+	}
+
+	return PaymentIntent{
+		ProviderRef: providerRef,
+		Payload:     payload,
+	}, nil
+}
+
+// Confirm cannot ask the bank about a transfer, so the payment stays pending until the webhook arrives.
+func (p *PromptPayPaymentProvider) Confirm(payment models.Payment) (string, error) {
+	return payment.Status, nil
+}
+
+// Refund is a manual bank transfer back to the customer, there is nothing to call.
+func (p *PromptPayPaymentProvider) Refund(payment models.Payment) error {
+	return nil
+}
+
+func (p *PromptPayPaymentProvider) VerifyWebhook(body []byte, signature string) (PaymentWebhookEvent, error) {
+	return verifyWebhookSignature(p.WebhookSecret, body, signature)
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentSlipServiceImpl struct {
+	DB        *gorm.DB
+	Providers map[string]PaymentProvider
+}
+
+type PaymentSlipService interface {
+	UploadPaymentSlip(c *fiber.Ctx) error
+	GetOrderPaymentSlips(c *fiber.Ctx) error
+	GetPaymentSlipImage(c *fiber.Ctx) error
+	GetPaymentSlips(c *fiber.Ctx) error
+	ApprovePaymentSlip(c *fiber.Ctx) error
+	RejectPaymentSlip(c *fiber.Ctx) error
+}
+
+func NewPaymentSlipService(configClients configs.ConfigClients) PaymentSlipService {
+	return &PaymentSlipServiceImpl{
+		DB:        configClients.DB,
+		Providers: NewPaymentProviders(),
+	}
+}
+
+func (s *PaymentSlipServiceImpl) UploadPaymentSlip(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	file, ok := c.Locals("file").(*multipart.FileHeader)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "locals file error",
+			Error:   nil,
+		})
+	}
+
+	var order models.Order
+	if err := s.DB.Model(&models.Order{}).Where("id = ?", c.Params("id")).Preload("Cart").First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if order.Cart.UserId != user.ID {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	if order.Status != models.OrderStatusAwaitingPayment {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot upload slip for order with status %s", order.Status),
+			Error:   nil,
+		})
+	}
+
+	fileData, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "file open error",
+			Error:   err,
+		})
+	}
+	defer fileData.Close()
+
+	imgBytes, err := io.ReadAll(fileData)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "file read error",
+			Error:   err,
+		})
+	}
+
+	slip := models.PaymentSlip{
+		OrderID:      order.ID,
+		UploadedByID: user.ID,
+		Image:        imgBytes,
+		ContentType:  file.Header.Get("Content-Type"),
+		Status:       models.PaymentSlipStatusPending,
+	}
+
+	if err := s.DB.Create(&slip).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create payment slip error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "upload payment slip success",
+		Data:    slip,
+	})
+}
+
+func (s *PaymentSlipServiceImpl) GetOrderPaymentSlips(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	if _, err := findViewableOrder(s.DB, user, c.Params("id")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   nil,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	var slips []models.PaymentSlip
+	if err := s.DB.Model(&models.PaymentSlip{}).Omit("image").Where("order_id = ?", c.Params("id")).Order("created_at DESC").Find(&slips).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get payment slips error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get order payment slips success",
+		Data:    slips,
+	})
+}
+
+func (s *PaymentSlipServiceImpl) GetPaymentSlipImage(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	querySlip := s.DB.Model(&models.PaymentSlip{}).Where("payment_slips.id = ?", c.Params("id")).Scopes(scopeOrderOwner(user, "payment_slips.order_id"))
+
+	var slip models.PaymentSlip
+	if err := querySlip.First(&slip).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "payment slip not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get payment slip error",
+			Error:   err,
+		})
+	}
+
+	c.Set("Content-Type", slip.ContentType)
+
+	return c.Send(slip.Image)
+}
+
+func (s *PaymentSlipServiceImpl) GetPaymentSlips(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	status := c.Query("status", models.PaymentSlipStatusPending)
+
+	querySlips := s.DB.Model(&models.PaymentSlip{}).Omit("image").Where("status = ?", status)
+
+	if query.PageSize > 0 {
+		querySlips = querySlips.Offset(query.Page * query.PageSize).Limit(query.PageSize)
+	}
+
+	var slips []models.PaymentSlip
+	if err := querySlips.Order("created_at ASC").Find(&slips).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get payment slips error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get payment slips success",
+		Data:    slips,
+	})
+}
+
+func (s *PaymentSlipServiceImpl) ApprovePaymentSlip(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	slip, err := lockPendingPaymentSlip(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handlePaymentSlipLockError(c, err)
+	}
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", slip.OrderID).First(&order).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get order failed",
+			Error:   err,
+		})
+	}
+
+	if order.Status != models.OrderStatusAwaitingPayment {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot approve slip for order with status %s", order.Status),
+			Error:   nil,
+		})
+	}
+
+	payment, err := findOrCreatePromptPayPayment(tx, s.Providers["promptpay"], order)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "create promptpay payment failed",
+			Error:   err,
+		})
+	}
+
+	payment.SlipRef = fmt.Sprintf("SLIP-%d", slip.ID)
+
+	if err := tx.Model(&models.Payment{}).Where("id = ?", payment.ID).Update("slip_ref", payment.SlipRef).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update slip reference failed",
+			Error:   err,
+		})
+	}
+
+	if err := applyPaymentStatus(tx, &payment, models.PaymentStatusSucceeded, user.ID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update payment status failed",
+			Error:   err,
+		})
+	}
+
+	if err := reviewPaymentSlip(tx, &slip, models.PaymentSlipStatusApproved, user.ID, ""); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update payment slip failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "approve payment slip success",
+		Data:    slip,
+	})
+}
+
+func (s *PaymentSlipServiceImpl) RejectPaymentSlip(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestRejectPaymentSlip)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	slip, err := lockPendingPaymentSlip(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handlePaymentSlipLockError(c, err)
+	}
+
+	if err := reviewPaymentSlip(tx, &slip, models.PaymentSlipStatusRejected, user.ID, req.Reason); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update payment slip failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "reject payment slip success",
+		Data:    slip,
+	})
+}
+
+var errPaymentSlipReviewed = errors.New("payment slip already reviewed")
+
+func lockPendingPaymentSlip(tx *gorm.DB, id string) (models.PaymentSlip, error) {
+	var slip models.PaymentSlip
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Omit("image").Where("id = ?", id).First(&slip).Error; err != nil {
+		return slip, err
+	}
+
+	if slip.Status != models.PaymentSlipStatusPending {
+		return slip, errPaymentSlipReviewed
+	}
+
+	return slip, nil
+}
+
+func handlePaymentSlipLockError(c *fiber.Ctx, err error) error {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "payment slip not found",
+			Error:   err,
+		})
+	case errPaymentSlipReviewed:
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "payment slip already reviewed",
+			Error:   err,
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get payment slip error",
+			Error:   err,
+		})
+	}
+}
+
+func reviewPaymentSlip(tx *gorm.DB, slip *models.PaymentSlip, status string, reviewedByID uint, reason string) error {
+	now := time.Now()
+
+	slip.Status = status
+	slip.RejectReason = reason
+	slip.ReviewedByID = &reviewedByID
+	slip.ReviewedAt = &now
+
+	return tx.Model(&models.PaymentSlip{}).Where("id = ?", slip.ID).Updates(map[string]interface{}{
+		"status":         slip.Status,
+		"reject_reason":  slip.RejectReason,
+		"reviewed_by_id": reviewedByID,
+		"reviewed_at":    now,
+	}).Error
+}
@@ -9,12 +9,15 @@ import (
 	"github.com/BaimhonS/kab-phone/utils"
 	"github.com/BaimhonS/kab-phone/validates"
 	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PhoneServiceImpl struct {
-	DB *gorm.DB
+	DB    *gorm.DB
+	Redis *redis.Client
 }
 
 type PhoneService interface {
@@ -23,11 +26,15 @@ type PhoneService interface {
 	CreatePhone(c *fiber.Ctx) error
 	UpdatePhone(c *fiber.Ctx) error
 	DeletePhone(c *fiber.Ctx) error
+	GetStockMovements(c *fiber.Ctx) error
+	AdjustStock(c *fiber.Ctx) error
+	GetLowStockPhones(c *fiber.Ctx) error
 }
 
 func NewPhoneService(configClients configs.ConfigClients) PhoneService {
 	return &PhoneServiceImpl{
-		DB: configClients.DB,
+		DB:    configClients.DB,
+		Redis: configClients.Redis,
 	}
 }
 
@@ -48,13 +55,31 @@ func (s *PhoneServiceImpl) GetPhones(c *fiber.Ctx) error {
 		queryPhones = queryPhones.Where("model_name LIKE ?", "%"+query.Search+"%")
 	}
 
-	if err := queryPhones.Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&phones).Error; err != nil {
+	if err := queryPhones.Preload("WarrantyTerms").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&phones).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
 			Message: "database get phones error",
 			Error:   err,
 		})
 	}
 
+	phoneIDs := make([]uint, 0, len(phones))
+	for _, phone := range phones {
+		phoneIDs = append(phoneIDs, phone.ID)
+	}
+
+	reserved, err := getReservedStock(c.Context(), s.Redis, phoneIDs, 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get reserved stock error",
+			Error:   err,
+		})
+	}
+
+	for i := range phones {
+		phones[i].Reserved = reserved[phones[i].ID]
+		phones[i].Available = max(phones[i].Amount-phones[i].Reserved, 0)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
 		Message: "get phones success",
 		Data:    phones,
@@ -112,22 +137,77 @@ func (s *PhoneServiceImpl) CreatePhone(c *fiber.Ctx) error {
 		})
 	}
 
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+			Error:   nil,
+		})
+	}
+
+	if req.Barcode != "" {
+		inUse, err := barcodeInUse(s.DB, req.Barcode, 0)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database get phone error",
+				Error:   err,
+			})
+		}
+
+		if inUse {
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: "barcode already used by another phone",
+				Error:   nil,
+			})
+		}
+	}
+
+	// the opening stock goes in through the ledger below
 	phone := models.Phone{
-		ModelName: req.ModelName,
-		BrandName: req.BrandName,
-		OS:        req.OS,
-		Price:     req.Price,
-		Amount:    req.Amount,
-		Image:     imgBytes,
+		ModelName:    req.ModelName,
+		BrandName:    req.BrandName,
+		OS:           req.OS,
+		Price:        req.Price,
+		ReorderPoint: req.ReorderPoint,
+		Barcode:      optionalString(req.Barcode),
+		Weight:       req.Weight,
+		Width:        req.Width,
+		Height:       req.Height,
+		Depth:        req.Depth,
+		Image:        imgBytes,
 	}
 
-	if err := s.DB.Create(&phone).Error; err != nil {
+	tx := s.DB.Begin()
+
+	if err := tx.Create(&phone).Error; err != nil {
+		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
 			Message: "database create phone error",
 			Error:   err,
 		})
 	}
 
+	if req.Amount > 0 {
+		if err := adjustPhoneStock(tx, &models.StockMovement{
+			PhoneID:     phone.ID,
+			Type:        models.StockMovementReceipt,
+			Quantity:    req.Amount,
+			Note:        "opening stock",
+			CreatedByID: userIDOrNil(user.ID),
+		}); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "record stock movement error",
+				Error:   err,
+			})
+		}
+		phone.Amount = req.Amount
+	}
+
+	tx.Commit()
+
+	queueLowStockCheck(phone.ID)
+
 	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
 		Message: "create phone success",
 		Data:    phone,
@@ -143,6 +223,14 @@ func (s *PhoneServiceImpl) UpdatePhone(c *fiber.Ctx) error {
 		})
 	}
 
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+			Error:   nil,
+		})
+	}
+
 	file, ok := c.Locals("file").(*multipart.FileHeader)
 	var imgBytes []byte
 	if ok {
@@ -173,17 +261,83 @@ func (s *PhoneServiceImpl) UpdatePhone(c *fiber.Ctx) error {
 		imgBytes = phone.Image
 	}
 
-	if err := s.DB.Model(&models.Phone{}).Where("id = ?", c.Params("id")).Updates(models.Phone{
-		Price:  req.Price,
-		Amount: req.Amount,
-		Image:  imgBytes,
+	tx := s.DB.Begin()
+
+	var phone models.Phone
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Phone{}).Omit("image").Where("id = ?", c.Params("id")).First(&phone).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "phone not found error",
+			Error:   err,
+		})
+	}
+
+	if req.Barcode != "" {
+		inUse, err := barcodeInUse(tx, req.Barcode, phone.ID)
+		if err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database get phone error",
+				Error:   err,
+			})
+		}
+
+		if inUse {
+			tx.Rollback()
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: "barcode already used by another phone",
+				Error:   nil,
+			})
+		}
+	}
+
+	if err := tx.Model(&models.Phone{}).Where("id = ?", phone.ID).Updates(models.Phone{
+		Price:   req.Price,
+		Barcode: optionalString(req.Barcode),
+		Weight:  req.Weight,
+		Width:   req.Width,
+		Height:  req.Height,
+		Depth:   req.Depth,
+		Image:   imgBytes,
 	}).Error; err != nil {
+		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
 			Message: "database update phone error",
 			Error:   err,
 		})
 	}
 
+	if req.ReorderPoint != nil {
+		if err := tx.Model(&models.Phone{}).Where("id = ?", phone.ID).Update("reorder_point", *req.ReorderPoint).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database update phone error",
+				Error:   err,
+			})
+		}
+	}
+
+	// amount 0 keeps the stock as it is, like the other fields
+	if req.Amount != 0 && req.Amount != phone.Amount {
+		if err := adjustPhoneStock(tx, &models.StockMovement{
+			PhoneID:     phone.ID,
+			Type:        models.StockMovementAdjustment,
+			Quantity:    req.Amount - phone.Amount,
+			Note:        req.StockNote,
+			CreatedByID: userIDOrNil(user.ID),
+		}); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "record stock movement error",
+				Error:   err,
+			})
+		}
+	}
+
+	tx.Commit()
+
+	queueLowStockCheck(phone.ID)
+
 	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
 		Message: "update phone success",
 		Data:    nil,
@@ -203,3 +357,21 @@ func (s *PhoneServiceImpl) DeletePhone(c *fiber.Ctx) error {
 		Data:    nil,
 	})
 }
+
+// barcodeInUse also looks at deleted phones because the unique index covers them.
+func barcodeInUse(db *gorm.DB, barcode string, excludePhoneID uint) (bool, error) {
+	var count int64
+	if err := db.Unscoped().Model(&models.Phone{}).Where("barcode = ? AND id <> ?", barcode, excludePhoneID).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,575 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errOrderNotPacking   = errors.New("order is not packing")
+	errPhoneUnitsNotSold = errors.New("phone units were not sold on the item")
+)
+
+type PhoneUnitServiceImpl struct {
+	DB *gorm.DB
+}
+
+type PhoneUnitService interface {
+	RegisterPhoneUnits(c *fiber.Ctx) error
+	GetPhoneUnits(c *fiber.Ctx) error
+	GetPhoneUnitByIMEI(c *fiber.Ctx) error
+	GetOrderUnits(c *fiber.Ctx) error
+	AssignOrderUnits(c *fiber.Ctx) error
+	UnassignOrderUnit(c *fiber.Ctx) error
+}
+
+func NewPhoneUnitService(configClients configs.ConfigClients) PhoneUnitService {
+	return &PhoneUnitServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+// RegisterPhoneUnits records the handsets of a phone as they are unpacked, the stock counter is not touched.
+func (s *PhoneUnitServiceImpl) RegisterPhoneUnits(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestRegisterPhoneUnits)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	var phone models.Phone
+	if err := s.DB.Model(&models.Phone{}).Omit("image").Where("id = ?", c.Params("id")).First(&phone).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "phone not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone error",
+			Error:   err,
+		})
+	}
+
+	imeis := make([]string, 0, len(req.Units))
+	units := make([]models.PhoneUnit, 0, len(req.Units))
+	for _, unit := range req.Units {
+		if containsString(imeis, unit.IMEI) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("imei %s listed twice", unit.IMEI),
+				Error:   nil,
+			})
+		}
+		imeis = append(imeis, unit.IMEI)
+
+		units = append(units, models.PhoneUnit{
+			PhoneID: phone.ID,
+			IMEI:    unit.IMEI,
+			Serial:  unit.Serial,
+			Status:  models.PhoneUnitStatusInStock,
+		})
+	}
+
+	var registered []string
+	if err := s.DB.Model(&models.PhoneUnit{}).Where("imei IN ?", imeis).Pluck("imei", &registered).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone units error",
+			Error:   err,
+		})
+	}
+
+	if len(registered) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("imei already registered : %v", registered),
+			Error:   nil,
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	if err := tx.Create(&units).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create phone units error",
+			Error:   err,
+		})
+	}
+
+	if err := recordPhoneUnitHistories(tx, units, "", models.PhoneUnitStatusInStock); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create phone unit histories error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "register phone units success",
+		Data:    units,
+	})
+}
+
+func (s *PhoneUnitServiceImpl) GetPhoneUnits(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	queryUnits := s.DB.Model(&models.PhoneUnit{}).Where("phone_id = ?", c.Params("id"))
+	if status := c.Query("status"); status != "" {
+		queryUnits = queryUnits.Where("status = ?", status)
+	}
+	if query.Search != "" {
+		queryUnits = queryUnits.Where("imei LIKE ? OR serial LIKE ?", "%"+query.Search+"%", "%"+query.Search+"%")
+	}
+
+	var total int64
+	if err := queryUnits.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count phone units error",
+			Error:   err,
+		})
+	}
+
+	var units []models.PhoneUnit
+	if err := queryUnits.Order("id ASC").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&units).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone units error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
+		Message: "get phone units success",
+		Data:    units,
+		Total:   int(total),
+	})
+}
+
+// GetPhoneUnitByIMEI answers who bought the handset, for warranty claims and theft reports.
+// A unit restocked after a return answers with its last sale, the full trail is in its histories.
+func (s *PhoneUnitServiceImpl) GetPhoneUnitByIMEI(c *fiber.Ctx) error {
+	var unit models.PhoneUnit
+	if err := s.DB.Model(&models.PhoneUnit{}).Where("imei = ?", c.Params("imei")).First(&unit).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "phone unit not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone unit error",
+			Error:   err,
+		})
+	}
+
+	var phone models.Phone
+	if err := s.DB.Unscoped().Model(&models.Phone{}).Omit("image").Where("id = ?", unit.PhoneID).First(&phone).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone error",
+			Error:   err,
+		})
+	}
+
+	var histories []models.PhoneUnitStatusHistory
+	if err := s.DB.Model(&models.PhoneUnitStatusHistory{}).Where("phone_unit_id = ?", unit.ID).Order("id ASC").Find(&histories).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone unit histories error",
+			Error:   err,
+		})
+	}
+
+	// a restocked unit can be reserved on an order that has not shipped yet , the buyer is the last sale
+	var orderID *uint
+	if unit.Status == models.PhoneUnitStatusSold || unit.Status == models.PhoneUnitStatusReturned {
+		orderID = unit.OrderID
+	} else {
+		for _, history := range histories {
+			if history.ToStatus == models.PhoneUnitStatusSold {
+				orderID = history.OrderID
+			}
+		}
+	}
+
+	data := fiber.Map{
+		"unit":      unit,
+		"phone":     phone,
+		"histories": histories,
+		"order":     nil,
+		"buyer":     nil,
+	}
+
+	if orderID != nil {
+		var order models.Order
+		if err := s.DB.Unscoped().Model(&models.Order{}).Where("id = ?", *orderID).Preload("Cart.User", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).First(&order).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database get order error",
+				Error:   err,
+			})
+		}
+
+		data["order"] = fiber.Map{
+			"id":               order.ID,
+			"order_number":     order.OrderNumber,
+			"status":           order.Status,
+			"tracking_number":  order.TrackingNumber,
+			"shipping_carrier": order.ShippingCarrier,
+			"created_at":       order.CreatedAt,
+		}
+		data["buyer"] = fiber.Map{
+			"id":           order.Cart.User.ID,
+			"username":     order.Cart.User.Username,
+			"first_name":   order.Cart.User.FirstName,
+			"last_name":    order.Cart.User.LastName,
+			"phone_number": order.Cart.User.PhoneNumber,
+			"address":      order.Cart.User.Address,
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get phone unit success",
+		Data:    data,
+	})
+}
+
+func (s *PhoneUnitServiceImpl) GetOrderUnits(c *fiber.Ctx) error {
+	return respondOrderUnits(c, s.DB, "get order units success")
+}
+
+func respondOrderUnits(c *fiber.Ctx, db *gorm.DB, message string) error {
+	var units []models.PhoneUnit
+	if err := db.Model(&models.PhoneUnit{}).Where("order_id = ?", c.Params("id")).Order("item_id ASC, id ASC").Find(&units).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone units error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: message,
+		Data:    units,
+	})
+}
+
+// AssignOrderUnits puts scanned handsets on the lines of a packing order, they stay reserved until it ships.
+func (s *PhoneUnitServiceImpl) AssignOrderUnits(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestAssignPhoneUnits)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	order, err := lockPackingOrder(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handleLockPackingOrderError(c, err, order)
+	}
+
+	itemByID := make(map[uint]models.Item, len(order.Cart.Items))
+	for _, item := range order.Cart.Items {
+		itemByID[item.ID] = item
+	}
+
+	type assignedCount struct {
+		ItemID uint
+		Count  int
+	}
+
+	var counts []assignedCount
+	if err := tx.Model(&models.PhoneUnit{}).Select("item_id, COUNT(*) AS count").Where("order_id = ?", order.ID).Group("item_id").Scan(&counts).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count phone units error",
+			Error:   err,
+		})
+	}
+
+	assignedByItemID := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		assignedByItemID[count.ItemID] = count.Count
+	}
+
+	for _, assignment := range req.Assignments {
+		item, ok := itemByID[assignment.ItemID]
+		if !ok {
+			tx.Rollback()
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("item %d is not on this order", assignment.ItemID),
+				Error:   nil,
+			})
+		}
+
+		var unit models.PhoneUnit
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("imei = ?", assignment.IMEI).First(&unit).Error; err != nil {
+			tx.Rollback()
+			if err == gorm.ErrRecordNotFound {
+				return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+					Message: fmt.Sprintf("imei %s not registered", assignment.IMEI),
+					Error:   err,
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database get phone unit error",
+				Error:   err,
+			})
+		}
+
+		// scanning the same handset twice is harmless
+		if unit.ItemID != nil && *unit.ItemID == item.ID && unit.Status == models.PhoneUnitStatusReserved {
+			continue
+		}
+
+		if unit.PhoneID != item.PhoneID {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("imei %s is not a %s %s", assignment.IMEI, item.BrandName, item.ModelName),
+				Error:   nil,
+			})
+		}
+
+		if unit.Status != models.PhoneUnitStatusInStock {
+			tx.Rollback()
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("imei %s is %s", assignment.IMEI, unit.Status),
+				Error:   nil,
+			})
+		}
+
+		if assignedByItemID[item.ID] >= item.Amount {
+			tx.Rollback()
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("item %d already has all %d units assigned", item.ID, item.Amount),
+				Error:   nil,
+			})
+		}
+
+		if err := tx.Model(&models.PhoneUnit{}).Where("id = ?", unit.ID).Updates(map[string]interface{}{
+			"status":   models.PhoneUnitStatusReserved,
+			"item_id":  item.ID,
+			"order_id": order.ID,
+		}).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database update phone unit error",
+				Error:   err,
+			})
+		}
+
+		unit.ItemID = &item.ID
+		unit.OrderID = &order.ID
+		if err := recordPhoneUnitHistories(tx, []models.PhoneUnit{unit}, models.PhoneUnitStatusInStock, models.PhoneUnitStatusReserved); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database create phone unit histories error",
+				Error:   err,
+			})
+		}
+
+		assignedByItemID[item.ID]++
+	}
+
+	tx.Commit()
+
+	return respondOrderUnits(c, s.DB, "assign order units success")
+}
+
+// UnassignOrderUnit takes a wrongly scanned handset off a packing order.
+func (s *PhoneUnitServiceImpl) UnassignOrderUnit(c *fiber.Ctx) error {
+	tx := s.DB.Begin()
+
+	order, err := lockPackingOrder(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handleLockPackingOrderError(c, err, order)
+	}
+
+	var unit models.PhoneUnit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("imei = ? AND order_id = ? AND status = ?", c.Params("imei"), order.ID, models.PhoneUnitStatusReserved).First(&unit).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "imei is not assigned to this order",
+				Error:   nil,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone unit error",
+			Error:   err,
+		})
+	}
+
+	if err := tx.Model(&models.PhoneUnit{}).Where("id = ?", unit.ID).Updates(map[string]interface{}{
+		"status":   models.PhoneUnitStatusInStock,
+		"item_id":  nil,
+		"order_id": nil,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database update phone unit error",
+			Error:   err,
+		})
+	}
+
+	if err := recordPhoneUnitHistories(tx, []models.PhoneUnit{unit}, models.PhoneUnitStatusReserved, models.PhoneUnitStatusInStock); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create phone unit histories error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return respondOrderUnits(c, s.DB, "unassign order unit success")
+}
+
+// syncOrderUnits follows the order status on its units: shipping sells the reserved handsets and
+// cancelling or refunding before that puts them back on the shelf. Sold units only come back through returns.
+func syncOrderUnits(tx *gorm.DB, orderID uint, status string) error {
+	var updates map[string]interface{}
+	switch status {
+	case models.OrderStatusShipped:
+		updates = map[string]interface{}{
+			"status":  models.PhoneUnitStatusSold,
+			"sold_at": time.Now(),
+		}
+	case models.OrderStatusCancelled, models.OrderStatusRefunded:
+		updates = map[string]interface{}{
+			"status":   models.PhoneUnitStatusInStock,
+			"item_id":  nil,
+			"order_id": nil,
+		}
+	default:
+		return nil
+	}
+
+	var units []models.PhoneUnit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ? AND status = ?", orderID, models.PhoneUnitStatusReserved).Find(&units).Error; err != nil {
+		return err
+	}
+
+	if len(units) == 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.PhoneUnit{}).Where("id IN ?", phoneUnitIDs(units)).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return recordPhoneUnitHistories(tx, units, models.PhoneUnitStatusReserved, updates["status"].(string))
+}
+
+// returnPhoneUnits takes handsets back from a return, restocked units can be sold again while the others
+// keep the order line they were sold on. Either way the sale stays in the unit's histories.
+func returnPhoneUnits(tx *gorm.DB, itemID uint, imeis []string, restock bool) error {
+	updates := map[string]interface{}{
+		"status": models.PhoneUnitStatusReturned,
+	}
+	if restock {
+		updates = map[string]interface{}{
+			"status":   models.PhoneUnitStatusInStock,
+			"item_id":  nil,
+			"order_id": nil,
+			"sold_at":  nil,
+		}
+	}
+
+	var units []models.PhoneUnit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("imei IN ? AND item_id = ? AND status = ?", imeis, itemID, models.PhoneUnitStatusSold).Find(&units).Error; err != nil {
+		return err
+	}
+
+	distinct := make(map[string]bool, len(imeis))
+	for _, imei := range imeis {
+		distinct[imei] = true
+	}
+
+	if len(units) != len(distinct) {
+		return errPhoneUnitsNotSold
+	}
+
+	if err := tx.Model(&models.PhoneUnit{}).Where("id IN ?", phoneUnitIDs(units)).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return recordPhoneUnitHistories(tx, units, models.PhoneUnitStatusSold, updates["status"].(string))
+}
+
+// recordPhoneUnitHistories appends the status change of each unit with the order line it was on or is put on.
+func recordPhoneUnitHistories(tx *gorm.DB, units []models.PhoneUnit, fromStatus string, toStatus string) error {
+	histories := make([]models.PhoneUnitStatusHistory, 0, len(units))
+	for _, unit := range units {
+		histories = append(histories, models.PhoneUnitStatusHistory{
+			PhoneUnitID: unit.ID,
+			FromStatus:  fromStatus,
+			ToStatus:    toStatus,
+			ItemID:      unit.ItemID,
+			OrderID:     unit.OrderID,
+		})
+	}
+
+	return tx.Create(&histories).Error
+}
+
+func phoneUnitIDs(units []models.PhoneUnit) []uint {
+	ids := make([]uint, 0, len(units))
+	for _, unit := range units {
+		ids = append(ids, unit.ID)
+	}
+
+	return ids
+}
+
+func lockPackingOrder(tx *gorm.DB, id string) (models.Order, error) {
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Preload("Cart.Items").First(&order).Error; err != nil {
+		return order, err
+	}
+
+	if order.Status != models.OrderStatusPacking {
+		return order, errOrderNotPacking
+	}
+
+	return order, nil
+}
+
+func handleLockPackingOrderError(c *fiber.Ctx, err error, order models.Order) error {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   err,
+		})
+	case errOrderNotPacking:
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("units can only be assigned while the order is packing, status is %s", order.Status),
+			Error:   err,
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+}
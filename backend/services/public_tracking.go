@@ -0,0 +1,144 @@
+package services
+
+import (
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+type (
+	PublicOrderTracking struct {
+		OrderNumber     string                     `json:"order_number"`
+		Status          string                     `json:"status"`
+		BuyerName       string                     `json:"buyer_name"`
+		ShippingCarrier string                     `json:"shipping_carrier"`
+		TrackingNumber  string                     `json:"tracking_number"`
+		Items           []PublicOrderTrackingItem  `json:"items"`
+		ShipmentEvents  []PublicOrderTrackingEvent `json:"shipment_events"`
+		CreatedAt       time.Time                  `json:"created_at"`
+	}
+
+	PublicOrderTrackingItem struct {
+		BrandName string `json:"brand_name"`
+		ModelName string `json:"model_name"`
+		Amount    int    `json:"amount"`
+	}
+
+	PublicOrderTrackingEvent struct {
+		Status      string    `json:"status"`
+		Description string    `json:"description"`
+		Location    string    `json:"location"`
+		OccurredAt  time.Time `json:"occurred_at"`
+	}
+)
+
+// GetPublicOrderTracking lets anyone holding the order number and the buyer's phone number follow
+// the parcel, prices, address and contact details are left out of the answer.
+func (s *OrderServiceImpl) GetPublicOrderTracking(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestPublicOrderTracking)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	orderNumber := strings.ToUpper(req.OrderNumber)
+	if !utils.ValidateOrderNumber(orderNumber) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	var order models.Order
+	if err := s.DB.Model(&models.Order{}).Where("order_number = ?", orderNumber).Preload("Cart").Preload("Cart.User").Preload("Cart.Items").Preload("ShipmentEvents", func(db *gorm.DB) *gorm.DB {
+		return db.Order("occurred_at ASC, id ASC")
+	}).First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	buyer := order.Cart.User
+	// a wrong phone number answers like an unknown order so numbers cannot be confirmed one by one
+	if subtle.ConstantTimeCompare([]byte(normalizeThaiPhoneNumber(buyer.PhoneNumber)), []byte(normalizeThaiPhoneNumber(req.PhoneNumber))) != 1 {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	tracking := PublicOrderTracking{
+		OrderNumber:     order.OrderNumber,
+		Status:          order.Status,
+		BuyerName:       maskName(buyer.FirstName) + " " + maskName(buyer.LastName),
+		ShippingCarrier: order.ShippingCarrier,
+		TrackingNumber:  order.TrackingNumber,
+		Items:           make([]PublicOrderTrackingItem, 0, len(order.Cart.Items)),
+		ShipmentEvents:  make([]PublicOrderTrackingEvent, 0, len(order.ShipmentEvents)),
+		CreatedAt:       order.CreatedAt,
+	}
+
+	for _, item := range order.Cart.Items {
+		tracking.Items = append(tracking.Items, PublicOrderTrackingItem{
+			BrandName: item.BrandName,
+			ModelName: item.ModelName,
+			Amount:    item.Amount,
+		})
+	}
+
+	for _, event := range order.ShipmentEvents {
+		tracking.ShipmentEvents = append(tracking.ShipmentEvents, PublicOrderTrackingEvent{
+			Status:      event.Status,
+			Description: event.Description,
+			Location:    event.Location,
+			OccurredAt:  event.OccurredAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get order tracking success",
+		Data:    tracking,
+	})
+}
+
+// normalizeThaiPhoneNumber keeps digits only and writes +66 numbers in their local 0 form.
+func normalizeThaiPhoneNumber(phoneNumber string) string {
+	var digits strings.Builder
+	for _, r := range phoneNumber {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+
+	normalized := digits.String()
+	if strings.HasPrefix(normalized, "66") && len(normalized) == 11 {
+		normalized = "0" + normalized[2:]
+	}
+
+	return normalized
+}
+
+// maskName keeps the first letter of a name, e.g. Somchai becomes S*****.
+func maskName(name string) string {
+	runes := []rune(name)
+	if len(runes) == 0 {
+		return ""
+	}
+
+	return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+}
@@ -0,0 +1,498 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errPurchaseOrderStatus = errors.New("purchase order status does not allow this action")
+
+type PurchaseOrderServiceImpl struct {
+	DB *gorm.DB
+}
+
+type PurchaseOrderService interface {
+	GetPurchaseOrders(c *fiber.Ctx) error
+	GetPurchaseOrderByID(c *fiber.Ctx) error
+	CreatePurchaseOrder(c *fiber.Ctx) error
+	UpdatePurchaseOrder(c *fiber.Ctx) error
+	SendPurchaseOrder(c *fiber.Ctx) error
+	ReceivePurchaseOrder(c *fiber.Ctx) error
+	CancelPurchaseOrder(c *fiber.Ctx) error
+}
+
+func NewPurchaseOrderService(configClients configs.ConfigClients) PurchaseOrderService {
+	return &PurchaseOrderServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+func (s *PurchaseOrderServiceImpl) GetPurchaseOrders(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	queryPurchaseOrders := s.DB.Model(&models.PurchaseOrder{})
+	if status := c.Query("status"); status != "" {
+		queryPurchaseOrders = queryPurchaseOrders.Where("status = ?", status)
+	}
+	if supplierID := c.Query("supplier_id"); supplierID != "" {
+		queryPurchaseOrders = queryPurchaseOrders.Where("supplier_id = ?", supplierID)
+	}
+
+	var total int64
+	if err := queryPurchaseOrders.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count purchase orders error",
+			Error:   err,
+		})
+	}
+
+	var purchaseOrders []models.PurchaseOrder
+	if err := queryPurchaseOrders.Preload("Supplier", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Order("id DESC").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&purchaseOrders).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get purchase orders error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
+		Message: "get purchase orders success",
+		Data:    purchaseOrders,
+		Total:   int(total),
+	})
+}
+
+func (s *PurchaseOrderServiceImpl) GetPurchaseOrderByID(c *fiber.Ctx) error {
+	return respondPurchaseOrder(c, s.DB, c.Params("id"), fiber.StatusOK, "get purchase order success")
+}
+
+func (s *PurchaseOrderServiceImpl) CreatePurchaseOrder(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestSavePurchaseOrder)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	if status, message, err := checkPurchaseOrderRequest(s.DB, req); err != nil {
+		return c.Status(status).JSON(utils.ErrorResponse{
+			Message: message,
+			Error:   err,
+		})
+	}
+
+	purchaseOrder := models.PurchaseOrder{
+		SupplierID:  req.SupplierID,
+		Status:      models.PurchaseOrderStatusDraft,
+		Note:        req.Note,
+		Lines:       purchaseOrderLines(req),
+		CreatedByID: user.ID,
+	}
+
+	if err := s.DB.Create(&purchaseOrder).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create purchase order error",
+			Error:   err,
+		})
+	}
+
+	return respondPurchaseOrder(c, s.DB, strconv.FormatUint(uint64(purchaseOrder.ID), 10), fiber.StatusCreated, "create purchase order success")
+}
+
+// UpdatePurchaseOrder replaces the supplier , note and lines of a draft.
+func (s *PurchaseOrderServiceImpl) UpdatePurchaseOrder(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestSavePurchaseOrder)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	if status, message, err := checkPurchaseOrderRequest(s.DB, req); err != nil {
+		return c.Status(status).JSON(utils.ErrorResponse{
+			Message: message,
+			Error:   err,
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	purchaseOrder, err := lockPurchaseOrder(tx, c.Params("id"), models.PurchaseOrderStatusDraft)
+	if err != nil {
+		tx.Rollback()
+		return handleLockPurchaseOrderError(c, err)
+	}
+
+	if err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", purchaseOrder.ID).Updates(map[string]interface{}{
+		"supplier_id": req.SupplierID,
+		"note":        req.Note,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database update purchase order error",
+			Error:   err,
+		})
+	}
+
+	if err := tx.Where("purchase_order_id = ?", purchaseOrder.ID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database delete purchase order lines error",
+			Error:   err,
+		})
+	}
+
+	lines := purchaseOrderLines(req)
+	for i := range lines {
+		lines[i].PurchaseOrderID = purchaseOrder.ID
+	}
+
+	if err := tx.Create(&lines).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create purchase order lines error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return respondPurchaseOrder(c, s.DB, c.Params("id"), fiber.StatusOK, "update purchase order success")
+}
+
+func (s *PurchaseOrderServiceImpl) SendPurchaseOrder(c *fiber.Ctx) error {
+	tx := s.DB.Begin()
+
+	purchaseOrder, err := lockPurchaseOrder(tx, c.Params("id"), models.PurchaseOrderStatusDraft)
+	if err != nil {
+		tx.Rollback()
+		return handleLockPurchaseOrderError(c, err)
+	}
+
+	if err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", purchaseOrder.ID).Updates(map[string]interface{}{
+		"status":  models.PurchaseOrderStatusSent,
+		"sent_at": time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database update purchase order error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return respondPurchaseOrder(c, s.DB, c.Params("id"), fiber.StatusOK, "send purchase order success")
+}
+
+// ReceivePurchaseOrder books delivered quantities into stock as receipt movements and moves the cost price
+// of each phone to the average of the stock on hand and the delivery. Lines cannot take more than was ordered.
+func (s *PurchaseOrderServiceImpl) ReceivePurchaseOrder(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestReceivePurchaseOrder)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	purchaseOrder, err := lockPurchaseOrder(tx, c.Params("id"), models.PurchaseOrderStatusSent, models.PurchaseOrderStatusPartiallyReceived)
+	if err != nil {
+		tx.Rollback()
+		return handleLockPurchaseOrderError(c, err)
+	}
+
+	var lines []models.PurchaseOrderLine
+	if err := tx.Where("purchase_order_id = ?", purchaseOrder.ID).Order("phone_id ASC").Find(&lines).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get purchase order lines error",
+			Error:   err,
+		})
+	}
+
+	quantityByLineID := make(map[uint]int, len(req.Lines))
+	for _, receipt := range req.Lines {
+		quantityByLineID[receipt.LineID] += receipt.Quantity
+	}
+
+	for lineID := range quantityByLineID {
+		if !containsLine(lines, lineID) {
+			tx.Rollback()
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("line %d is not on this purchase order", lineID),
+				Error:   nil,
+			})
+		}
+	}
+
+	note := fmt.Sprintf("purchase order %d", purchaseOrder.ID)
+	phoneIDs := make([]uint, 0, len(quantityByLineID))
+	fullyReceived := true
+
+	// lines are walked in phone id order so the phone locks are always taken in the same order
+	for i, line := range lines {
+		quantity := quantityByLineID[line.ID]
+		if quantity > 0 {
+			if remaining := line.ExpectedQuantity - line.ReceivedQuantity; quantity > remaining {
+				tx.Rollback()
+				return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+					Message: fmt.Sprintf("line %d has only %d left to receive", line.ID, max(remaining, 0)),
+					Error:   nil,
+				})
+			}
+
+			var phone models.Phone
+			if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.Phone{}).Select("id", "amount", "cost_price").Where("id = ?", line.PhoneID).First(&phone).Error; err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+					Message: "database get phone error",
+					Error:   err,
+				})
+			}
+
+			if err := tx.Unscoped().Model(&models.Phone{}).Where("id = ?", phone.ID).Update("cost_price", movingAverageCost(phone.Amount, phone.CostPrice, quantity, line.CostPrice)).Error; err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+					Message: "database update phone cost price error",
+					Error:   err,
+				})
+			}
+
+			if err := adjustPhoneStock(tx, &models.StockMovement{
+				PhoneID:         phone.ID,
+				Type:            models.StockMovementReceipt,
+				Quantity:        quantity,
+				PurchaseOrderID: &purchaseOrder.ID,
+				Note:            note,
+				CreatedByID:     userIDOrNil(user.ID),
+			}); err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+					Message: "record stock movement failed",
+					Error:   err,
+				})
+			}
+
+			lines[i].ReceivedQuantity += quantity
+			if err := tx.Model(&models.PurchaseOrderLine{}).Where("id = ?", line.ID).Update("received_quantity", lines[i].ReceivedQuantity).Error; err != nil {
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+					Message: "database update purchase order line error",
+					Error:   err,
+				})
+			}
+
+			phoneIDs = append(phoneIDs, phone.ID)
+		}
+
+		if lines[i].ReceivedQuantity < lines[i].ExpectedQuantity {
+			fullyReceived = false
+		}
+	}
+
+	updates := map[string]interface{}{
+		"status": models.PurchaseOrderStatusPartiallyReceived,
+	}
+	if fullyReceived {
+		updates["status"] = models.PurchaseOrderStatusReceived
+		updates["received_at"] = time.Now()
+	}
+
+	if err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", purchaseOrder.ID).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database update purchase order error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	queueLowStockCheck(phoneIDs...)
+
+	return respondPurchaseOrder(c, s.DB, c.Params("id"), fiber.StatusOK, "receive purchase order success")
+}
+
+// CancelPurchaseOrder is only allowed before anything came in, a partly delivered order stays as the record of it.
+func (s *PurchaseOrderServiceImpl) CancelPurchaseOrder(c *fiber.Ctx) error {
+	tx := s.DB.Begin()
+
+	purchaseOrder, err := lockPurchaseOrder(tx, c.Params("id"), models.PurchaseOrderStatusDraft, models.PurchaseOrderStatusSent)
+	if err != nil {
+		tx.Rollback()
+		return handleLockPurchaseOrderError(c, err)
+	}
+
+	if err := tx.Model(&models.PurchaseOrder{}).Where("id = ?", purchaseOrder.ID).Update("status", models.PurchaseOrderStatusCancelled).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database update purchase order error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return respondPurchaseOrder(c, s.DB, c.Params("id"), fiber.StatusOK, "cancel purchase order success")
+}
+
+// checkPurchaseOrderRequest makes sure the supplier is active and every line names a different existing phone.
+func checkPurchaseOrderRequest(db *gorm.DB, req validates.RequestSavePurchaseOrder) (int, string, error) {
+	var supplier models.Supplier
+	if err := db.Model(&models.Supplier{}).Where("id = ? AND active = ?", req.SupplierID, true).First(&supplier).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fiber.StatusNotFound, "supplier not found or inactive", err
+		}
+		return fiber.StatusInternalServerError, "database get supplier error", err
+	}
+
+	phoneIDs := make([]uint, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		phoneIDs = append(phoneIDs, line.PhoneID)
+	}
+
+	if countDistinct(phoneIDs) != len(phoneIDs) {
+		return fiber.StatusBadRequest, "each phone can only be listed once", errors.New("phone listed twice")
+	}
+
+	var phoneCount int64
+	if err := db.Model(&models.Phone{}).Where("id IN ?", phoneIDs).Count(&phoneCount).Error; err != nil {
+		return fiber.StatusInternalServerError, "database get phones error", err
+	}
+
+	if int(phoneCount) != len(phoneIDs) {
+		return fiber.StatusNotFound, "some phones not found", gorm.ErrRecordNotFound
+	}
+
+	return fiber.StatusOK, "", nil
+}
+
+func purchaseOrderLines(req validates.RequestSavePurchaseOrder) []models.PurchaseOrderLine {
+	lines := make([]models.PurchaseOrderLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, models.PurchaseOrderLine{
+			PhoneID:          line.PhoneID,
+			CostPrice:        line.CostPrice,
+			ExpectedQuantity: line.ExpectedQuantity,
+		})
+	}
+
+	return lines
+}
+
+// movingAverageCost weighs the cost of the stock on hand against the delivery, stock without a known cost
+// takes the delivery cost.
+func movingAverageCost(onHand int, onHandCost models.Money, received int, receivedCost models.Money) models.Money {
+	if onHand <= 0 || onHandCost == 0 {
+		return receivedCost
+	}
+
+	total := onHandCost.Mul(onHand) + receivedCost.Mul(received)
+	quantity := models.Money(onHand + received)
+
+	return (total + quantity/2) / quantity
+}
+
+func containsLine(lines []models.PurchaseOrderLine, lineID uint) bool {
+	for _, line := range lines {
+		if line.ID == lineID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func respondPurchaseOrder(c *fiber.Ctx, db *gorm.DB, id string, status int, message string) error {
+	var purchaseOrder models.PurchaseOrder
+	if err := db.Where("id = ?", id).Preload("Supplier", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Lines.Phone", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Omit("image")
+	}).First(&purchaseOrder).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "purchase order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get purchase order error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(status).JSON(utils.SuccessResponse{
+		Message: message,
+		Data:    purchaseOrder,
+	})
+}
+
+func lockPurchaseOrder(tx *gorm.DB, id string, statuses ...string) (models.PurchaseOrder, error) {
+	var purchaseOrder models.PurchaseOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&purchaseOrder).Error; err != nil {
+		return purchaseOrder, err
+	}
+
+	if !containsString(statuses, purchaseOrder.Status) {
+		return purchaseOrder, errPurchaseOrderStatus
+	}
+
+	return purchaseOrder, nil
+}
+
+func handleLockPurchaseOrderError(c *fiber.Ctx, err error) error {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "purchase order not found",
+			Error:   err,
+		})
+	case errPurchaseOrderStatus:
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "purchase order is not in a state that allows this action",
+			Error:   err,
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get purchase order error",
+			Error:   err,
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+func (s *OrderServiceImpl) GetOrderReceiptPDF(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	var order models.Order
+	if err := s.DB.Model(&models.Order{}).Where("id = ?", c.Params("id")).Preload("Cart").Preload("Cart.User").Preload("Cart.Items").First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if !canViewOrder(user, order) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	file, err := renderOrderReceiptPDF(order, utils.GetShopInfo())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "render receipt failed",
+			Error:   err,
+		})
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fmt.Sprintf("receipt-%s.pdf", order.OrderNumber)))
+
+	return c.Send(file)
+}
+
+func renderOrderReceiptPDF(order models.Order, shop utils.ShopInfo) ([]byte, error) {
+	pdf, font, err := utils.NewPDF()
+	if err != nil {
+		return nil, err
+	}
+
+	pdf.AddPage()
+
+	pdf.SetFont(font, "B", 16)
+	pdf.CellFormat(0, 8, utils.PDFLabel("ใบเสร็จรับเงิน", "RECEIPT"), "", 1, "C", false, 0, "")
+	pdf.Ln(2)
+
+	writePDFShopHeader(pdf, font, shop)
+
+	pdf.SetFont(font, "", 10)
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("เลขที่คำสั่งซื้อ", "Order No."), order.OrderNumber), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("วันที่", "Date"), utils.ParseToLocalTime(order.CreatedAt).Format("02/01/2006 15:04")), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("สถานะ", "Status"), order.Status), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("ขนส่ง", "Carrier"), valueOrDash(order.ShippingCarrier)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("เลขพัสดุ", "Tracking No."), valueOrDash(order.TrackingNumber)), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	buyer := order.Cart.User
+	pdf.SetFont(font, "B", 10)
+	pdf.CellFormat(0, 5, utils.PDFLabel("ผู้ซื้อ", "Buyer"), "", 1, "L", false, 0, "")
+	pdf.SetFont(font, "", 10)
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s %s", buyer.FirstName, buyer.LastName), "", 1, "L", false, 0, "")
+	if buyer.Address != "" {
+		pdf.MultiCell(0, 5, buyer.Address, "", "L", false)
+	}
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("โทร", "Tel"), buyer.PhoneNumber), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	writePDFItemTable(pdf, font, order.Cart.Items, order.ShippingFee)
+	writePDFTotals(pdf, font, order.Subtotal, order.VATRate, order.VATInclusive, order.VATAmount, order.TotalPrice)
+
+	return utils.OutputPDF(pdf)
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
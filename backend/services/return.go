@@ -0,0 +1,437 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnServiceImpl struct {
+	DB *gorm.DB
+}
+
+type ReturnService interface {
+	CreateReturn(c *fiber.Ctx) error
+	GetOrderReturns(c *fiber.Ctx) error
+	GetReturns(c *fiber.Ctx) error
+	ApproveReturn(c *fiber.Ctx) error
+	RejectReturn(c *fiber.Ctx) error
+	ReceiveReturn(c *fiber.Ctx) error
+	CompleteReturn(c *fiber.Ctx) error
+}
+
+func NewReturnService(configClients configs.ConfigClients) ReturnService {
+	return &ReturnServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+func (s *ReturnServiceImpl) CreateReturn(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestCreateReturn)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).Preload("Cart").First(&order).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if order.Cart.UserId != user.ID {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	if order.Status != models.OrderStatusDelivered {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "only delivered orders can be returned",
+			Error:   nil,
+		})
+	}
+
+	var item models.Item
+	if err := tx.Model(&models.Item{}).Where("id = ? AND cart_id = ?", req.ItemID, order.CartID).First(&item).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "item not found in order",
+			Error:   err,
+		})
+	}
+
+	var returnedQuantity int64
+	if err := tx.Model(&models.Return{}).Where("item_id = ? AND status <> ?", item.ID, models.ReturnStatusRejected).Select("COALESCE(SUM(quantity), 0)").Scan(&returnedQuantity).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get returned quantity error",
+			Error:   err,
+		})
+	}
+
+	if req.Quantity > item.Amount-int(returnedQuantity) {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("you can only return max %v of this item", item.Amount-int(returnedQuantity)),
+			Error:   nil,
+		})
+	}
+
+	returnRequest := models.Return{
+		OrderID:       order.ID,
+		ItemID:        item.ID,
+		Quantity:      req.Quantity,
+		Reason:        req.Reason,
+		Condition:     req.Condition,
+		Resolution:    req.Resolution,
+		Status:        models.ReturnStatusRequested,
+		RequestedByID: user.ID,
+	}
+
+	if err := tx.Create(&returnRequest).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create return error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	returnRequest.Item = item
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "create return success",
+		Data:    returnRequest,
+	})
+}
+
+func (s *ReturnServiceImpl) GetOrderReturns(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	if _, err := findViewableOrder(s.DB, user, c.Params("id")); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   nil,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	var returns []models.Return
+	if err := s.DB.Model(&models.Return{}).Where("order_id = ?", c.Params("id")).Preload("Item").Order("created_at DESC").Find(&returns).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get returns error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get order returns success",
+		Data:    returns,
+	})
+}
+
+func (s *ReturnServiceImpl) GetReturns(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	queryReturns := s.DB.Model(&models.Return{})
+
+	if status := c.Query("status"); status != "" {
+		queryReturns = queryReturns.Where("status = ?", status)
+	}
+
+	if query.PageSize > 0 {
+		queryReturns = queryReturns.Offset(query.Page * query.PageSize).Limit(query.PageSize)
+	}
+
+	var returns []models.Return
+	if err := queryReturns.Preload("Item").Order("created_at ASC").Find(&returns).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get returns error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get returns success",
+		Data:    returns,
+	})
+}
+
+func (s *ReturnServiceImpl) ApproveReturn(c *fiber.Ctx) error {
+	return s.reviewReturn(c, models.ReturnStatusApproved)
+}
+
+func (s *ReturnServiceImpl) RejectReturn(c *fiber.Ctx) error {
+	return s.reviewReturn(c, models.ReturnStatusRejected)
+}
+
+func (s *ReturnServiceImpl) reviewReturn(c *fiber.Ctx, status string) error {
+	req, ok := c.Locals("req").(validates.RequestReviewReturn)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	returnRequest, err := lockReturn(tx, c.Params("id"), models.ReturnStatusRequested)
+	if err != nil {
+		tx.Rollback()
+		return handleLockReturnError(c, err)
+	}
+
+	returnRequest.Status = status
+	returnRequest.AdminNote = req.Note
+	returnRequest.ReviewedByID = &user.ID
+
+	if err := tx.Model(&models.Return{}).Where("id = ?", returnRequest.ID).Updates(map[string]interface{}{
+		"status":         returnRequest.Status,
+		"admin_note":     returnRequest.AdminNote,
+		"reviewed_by_id": user.ID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update return failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "review return success",
+		Data:    returnRequest,
+	})
+}
+
+func (s *ReturnServiceImpl) ReceiveReturn(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestReceiveReturn)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	returnRequest, err := lockReturn(tx, c.Params("id"), models.ReturnStatusApproved)
+	if err != nil {
+		tx.Rollback()
+		return handleLockReturnError(c, err)
+	}
+
+	now := time.Now()
+	returnRequest.Status = models.ReturnStatusReceived
+	returnRequest.Restocked = req.Restock
+	returnRequest.ReceivedAt = &now
+
+	if err := tx.Model(&models.Return{}).Where("id = ?", returnRequest.ID).Updates(map[string]interface{}{
+		"status":      returnRequest.Status,
+		"restocked":   returnRequest.Restocked,
+		"received_at": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update return failed",
+			Error:   err,
+		})
+	}
+
+	if len(req.IMEIs) > 0 {
+		if len(req.IMEIs) > returnRequest.Quantity {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("only %d units were returned", returnRequest.Quantity),
+				Error:   nil,
+			})
+		}
+
+		if err := returnPhoneUnits(tx, returnRequest.ItemID, req.IMEIs, req.Restock); err != nil {
+			tx.Rollback()
+			if err == errPhoneUnitsNotSold {
+				return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+					Message: "some imeis were not sold on this order line",
+					Error:   err,
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "update phone units failed",
+				Error:   err,
+			})
+		}
+	}
+
+	if req.Restock {
+		if err := adjustPhoneStock(tx, &models.StockMovement{
+			PhoneID:     returnRequest.Item.PhoneID,
+			Type:        models.StockMovementReturn,
+			Quantity:    returnRequest.Quantity,
+			OrderID:     &returnRequest.OrderID,
+			ReturnID:    &returnRequest.ID,
+			CreatedByID: userIDOrNil(user.ID),
+		}); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "restock phone amount failed",
+				Error:   err,
+			})
+		}
+	}
+
+	tx.Commit()
+
+	if req.Restock {
+		queueLowStockCheck(returnRequest.Item.PhoneID)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "receive return success",
+		Data:    returnRequest,
+	})
+}
+
+func (s *ReturnServiceImpl) CompleteReturn(c *fiber.Ctx) error {
+	tx := s.DB.Begin()
+
+	returnRequest, err := lockReturn(tx, c.Params("id"), models.ReturnStatusReceived)
+	if err != nil {
+		tx.Rollback()
+		return handleLockReturnError(c, err)
+	}
+
+	now := time.Now()
+	returnRequest.Status = models.ReturnStatusCompleted
+	returnRequest.CompletedAt = &now
+
+	if returnRequest.Resolution == models.ReturnResolutionRefund {
+		returnRequest.RefundAmount = returnRequest.Item.UnitPrice.Mul(returnRequest.Quantity)
+	}
+
+	if err := tx.Model(&models.Return{}).Where("id = ?", returnRequest.ID).Updates(map[string]interface{}{
+		"status":        returnRequest.Status,
+		"refund_amount": returnRequest.RefundAmount,
+		"completed_at":  now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update return failed",
+			Error:   err,
+		})
+	}
+
+	if returnRequest.Resolution == models.ReturnResolutionRefund {
+		if err := releaseReturnedWarranties(tx, returnRequest.ItemID, returnRequest.Quantity); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "update warranties failed",
+				Error:   err,
+			})
+		}
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "complete return success",
+		Data:    returnRequest,
+	})
+}
+
+var errReturnStatus = errors.New("return status invalid")
+
+func lockReturn(tx *gorm.DB, id string, status string) (models.Return, error) {
+	var returnRequest models.Return
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Preload("Item").First(&returnRequest).Error; err != nil {
+		return returnRequest, err
+	}
+
+	if returnRequest.Status != status {
+		return returnRequest, errReturnStatus
+	}
+
+	return returnRequest, nil
+}
+
+func handleLockReturnError(c *fiber.Ctx, err error) error {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "return not found",
+			Error:   err,
+		})
+	case errReturnStatus:
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "return is not in a state that allows this action",
+			Error:   err,
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get return error",
+			Error:   err,
+		})
+	}
+}
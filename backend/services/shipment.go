@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var shipmentStatuses = []string{
+	models.ShipmentStatusPickedUp,
+	models.ShipmentStatusInTransit,
+	models.ShipmentStatusOutForDelivery,
+	models.ShipmentStatusFailedAttempt,
+	models.ShipmentStatusDelivered,
+	models.ShipmentStatusReturned,
+}
+
+type ShipmentServiceImpl struct {
+	DB       *gorm.DB
+	Carriers map[string]Carrier
+}
+
+type ShipmentService interface {
+	HandleShipmentWebhook(c *fiber.Ctx) error
+}
+
+func NewShipmentService(configClients configs.ConfigClients) ShipmentService {
+	return &ShipmentServiceImpl{
+		DB:       configClients.DB,
+		Carriers: NewCarriers(),
+	}
+}
+
+func (s *ShipmentServiceImpl) HandleShipmentWebhook(c *fiber.Ctx) error {
+	carrier, ok := s.Carriers[c.Query("carrier")]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "carrier not supported",
+			Error:   nil,
+		})
+	}
+
+	events, err := carrier.VerifyWebhook(c.Body(), c.Get("X-Carrier-Signature"))
+	if err != nil {
+		if err == ErrShipmentSignatureInvalid {
+			return c.Status(fiber.StatusUnauthorized).JSON(utils.ErrorResponse{
+				Message: "verify shipment webhook failed",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "shipment webhook body invalid",
+			Error:   err,
+		})
+	}
+
+	for _, event := range events {
+		if event.TrackingNumber == "" || event.OccurredAt.IsZero() || !containsString(shipmentStatuses, event.Status) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "shipment webhook event invalid",
+				Error:   nil,
+			})
+		}
+	}
+
+	tx := s.DB.Begin()
+
+	recorded := 0
+	unknown := 0
+	for _, event := range events {
+		created, err := recordShipmentEvent(tx, carrier.Code(), event)
+		if err == gorm.ErrRecordNotFound {
+			// acknowledged anyway so the carrier stops retrying parcels we did not ship
+			unknown++
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "record shipment event failed",
+				Error:   err,
+			})
+		}
+
+		if created {
+			recorded++
+		}
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "handle shipment webhook success",
+		Data: fiber.Map{
+			"received": len(events),
+			"recorded": recorded,
+			"unknown":  unknown,
+		},
+	})
+}
+
+// recordShipmentEvent stores the event against the order shipped with that tracking number and
+// marks a shipped order delivered, it returns false for events the carrier already sent.
+func recordShipmentEvent(tx *gorm.DB, carrierCode string, event ShipmentWebhookEvent) (bool, error) {
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("shipping_carrier = ? AND tracking_number = ?", carrierCode, event.TrackingNumber).First(&order).Error; err != nil {
+		return false, err
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ShipmentEvent{
+		OrderID:        order.ID,
+		Carrier:        carrierCode,
+		TrackingNumber: event.TrackingNumber,
+		Status:         event.Status,
+		RawStatus:      event.RawStatus,
+		Description:    event.Description,
+		Location:       event.Location,
+		OccurredAt:     event.OccurredAt,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if event.Status == models.ShipmentStatusDelivered && order.Status == models.OrderStatusShipped {
+		if err := changeOrderStatus(tx, &order, models.OrderStatusDelivered, 0, fmt.Sprintf("delivered by %s", carrierCode)); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
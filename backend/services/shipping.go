@@ -0,0 +1,358 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+var ErrShippingUnavailable = errors.New("shipping method not available for this cart")
+
+type ShippingQuote struct {
+	CarrierCode  string       `json:"carrier_code"`
+	CarrierName  string       `json:"carrier_name"`
+	Zone         string       `json:"zone"`
+	Weight       int          `json:"weight"`
+	Fee          models.Money `json:"fee"`
+	FreeShipping bool         `json:"free_shipping"`
+}
+
+type ShippingServiceImpl struct {
+	DB *gorm.DB
+}
+
+type ShippingService interface {
+	GetShippingQuotes(c *fiber.Ctx) error
+	GetShippingCarriers(c *fiber.Ctx) error
+	CreateShippingCarrier(c *fiber.Ctx) error
+	UpdateShippingCarrier(c *fiber.Ctx) error
+	CreateShippingRate(c *fiber.Ctx) error
+	UpdateShippingRate(c *fiber.Ctx) error
+	DeleteShippingRate(c *fiber.Ctx) error
+}
+
+func NewShippingService(configClients configs.ConfigClients) ShippingService {
+	return &ShippingServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+func (s *ShippingServiceImpl) GetShippingQuotes(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	var cart models.Cart
+	if err := s.DB.Model(&models.Cart{}).Where("user_id = ? AND status = ?", user.ID, "PENDING").Preload("Items").First(&cart).Error; err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "cart not found",
+			Error:   err,
+		})
+	}
+
+	if len(cart.Items) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "cart is empty",
+			Error:   nil,
+		})
+	}
+
+	phoneIDs := make([]uint, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		phoneIDs = append(phoneIDs, item.PhoneID)
+	}
+
+	var phones []models.Phone
+	if err := s.DB.Omit("image").Where("id IN ?", phoneIDs).Find(&phones).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "get phones failed",
+			Error:   err,
+		})
+	}
+
+	phoneByID := make(map[uint]models.Phone, len(phones))
+	for _, phone := range phones {
+		phoneByID[phone.ID] = phone
+	}
+
+	var itemsPrice models.Money
+	for _, item := range cart.Items {
+		itemsPrice += phoneByID[item.PhoneID].Price.Mul(item.Amount)
+	}
+
+	carriers, err := getActiveShippingCarriers(s.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get shipping carriers error",
+			Error:   err,
+		})
+	}
+
+	zone := utils.GetShippingZone(c.Query("province"))
+	weight := cartShippingWeight(cart.Items, phoneByID)
+
+	quotes := make([]ShippingQuote, 0, len(carriers))
+	for _, carrier := range carriers {
+		quote, err := quoteShipping(carrier, zone, weight, itemsPrice)
+		if err != nil {
+			continue
+		}
+		quotes = append(quotes, quote)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get shipping quotes success",
+		Data:    quotes,
+	})
+}
+
+func (s *ShippingServiceImpl) GetShippingCarriers(c *fiber.Ctx) error {
+	var carriers []models.ShippingCarrier
+	if err := s.DB.Model(&models.ShippingCarrier{}).Preload("Rates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("zone ASC, max_weight ASC")
+	}).Order("id ASC").Find(&carriers).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get shipping carriers error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get shipping carriers success",
+		Data:    carriers,
+	})
+}
+
+func (s *ShippingServiceImpl) CreateShippingCarrier(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestCreateShippingCarrier)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	carrier := models.ShippingCarrier{
+		Code:                  req.Code,
+		Name:                  req.Name,
+		Active:                true,
+		FreeShippingThreshold: req.FreeShippingThreshold,
+	}
+
+	if err := s.DB.Create(&carrier).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create shipping carrier error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "create shipping carrier success",
+		Data:    carrier,
+	})
+}
+
+func (s *ShippingServiceImpl) UpdateShippingCarrier(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestUpdateShippingCarrier)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if req.FreeShippingThreshold != nil {
+		updates["free_shipping_threshold"] = *req.FreeShippingThreshold
+	}
+
+	if len(updates) > 0 {
+		if err := s.DB.Model(&models.ShippingCarrier{}).Where("id = ?", c.Params("id")).Updates(updates).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database update shipping carrier error",
+				Error:   err,
+			})
+		}
+	}
+
+	var carrier models.ShippingCarrier
+	if err := s.DB.Model(&models.ShippingCarrier{}).Where("id = ?", c.Params("id")).Preload("Rates").First(&carrier).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "shipping carrier not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get shipping carrier error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "update shipping carrier success",
+		Data:    carrier,
+	})
+}
+
+func (s *ShippingServiceImpl) CreateShippingRate(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestShippingRate)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	var carrier models.ShippingCarrier
+	if err := s.DB.Model(&models.ShippingCarrier{}).Where("id = ?", c.Params("id")).First(&carrier).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "shipping carrier not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get shipping carrier error",
+			Error:   err,
+		})
+	}
+
+	rate := models.ShippingRate{
+		CarrierID: carrier.ID,
+		Zone:      req.Zone,
+		MaxWeight: req.MaxWeight,
+		Fee:       req.Fee,
+	}
+
+	if err := s.DB.Create(&rate).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create shipping rate error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "create shipping rate success",
+		Data:    rate,
+	})
+}
+
+func (s *ShippingServiceImpl) UpdateShippingRate(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestShippingRate)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	var rate models.ShippingRate
+	if err := s.DB.Model(&models.ShippingRate{}).Where("id = ?", c.Params("id")).First(&rate).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "shipping rate not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get shipping rate error",
+			Error:   err,
+		})
+	}
+
+	rate.Zone = req.Zone
+	rate.MaxWeight = req.MaxWeight
+	rate.Fee = req.Fee
+
+	if err := s.DB.Save(&rate).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database update shipping rate error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "update shipping rate success",
+		Data:    rate,
+	})
+}
+
+func (s *ShippingServiceImpl) DeleteShippingRate(c *fiber.Ctx) error {
+	result := s.DB.Delete(&models.ShippingRate{}, c.Params("id"))
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database delete shipping rate error",
+			Error:   result.Error,
+		})
+	}
+
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "shipping rate not found",
+			Error:   nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "delete shipping rate success",
+		Data:    nil,
+	})
+}
+
+// getActiveShippingCarriers loads active carriers with their rates sorted from the lightest bracket.
+func getActiveShippingCarriers(db *gorm.DB) ([]models.ShippingCarrier, error) {
+	var carriers []models.ShippingCarrier
+	err := db.Model(&models.ShippingCarrier{}).Where("active = ?", true).Preload("Rates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("max_weight ASC")
+	}).Order("id ASC").Find(&carriers).Error
+
+	return carriers, err
+}
+
+func cartShippingWeight(items []models.Item, phoneByID map[uint]models.Phone) int {
+	weight := 0
+	for _, item := range items {
+		weight += utils.ChargeableWeight(phoneByID[item.PhoneID]) * item.Amount
+	}
+
+	return weight
+}
+
+// quoteShipping picks the lightest bracket of the zone that fits the parcel,
+// rates must be sorted by max_weight ascending.
+func quoteShipping(carrier models.ShippingCarrier, zone string, weight int, itemsPrice models.Money) (ShippingQuote, error) {
+	for _, rate := range carrier.Rates {
+		if rate.Zone != zone || rate.MaxWeight < weight {
+			continue
+		}
+
+		quote := ShippingQuote{
+			CarrierCode: carrier.Code,
+			CarrierName: carrier.Name,
+			Zone:        zone,
+			Weight:      weight,
+			Fee:         rate.Fee,
+		}
+
+		if carrier.FreeShippingThreshold > 0 && itemsPrice >= carrier.FreeShippingThreshold {
+			quote.Fee = 0
+			quote.FreeShipping = true
+		}
+
+		return quote, nil
+	}
+
+	return ShippingQuote{}, ErrShippingUnavailable
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+var ErrInsufficientStock = errors.New("not enough stock")
+
+// adjustPhoneStock applies movement.Quantity to the phone and writes the movement to the ledger
+// with the resulting balance. Stock never goes below zero, a larger decrease fails with ErrInsufficientStock.
+// It must run inside the transaction that makes the change so the ledger and phones.amount move together.
+func adjustPhoneStock(tx *gorm.DB, movement *models.StockMovement) error {
+	queryPhone := tx.Unscoped().Model(&models.Phone{}).Where("id = ?", movement.PhoneID)
+	if movement.Quantity < 0 {
+		queryPhone = queryPhone.Where("amount >= ?", -movement.Quantity)
+	}
+
+	result := queryPhone.Update("amount", gorm.Expr("amount + ?", movement.Quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+
+	var phone models.Phone
+	if err := tx.Unscoped().Model(&models.Phone{}).Select("id", "amount").Where("id = ?", movement.PhoneID).First(&phone).Error; err != nil {
+		return err
+	}
+
+	movement.BalanceAfter = phone.Amount
+
+	return tx.Create(movement).Error
+}
+
+// userIDOrNil turns the 0 used for system changes into a nil foreign key.
+func userIDOrNil(userID uint) *uint {
+	if userID == 0 {
+		return nil
+	}
+
+	return &userID
+}
+
+// GetStockMovements lists the ledger of one phone newest first, next to the stock the ledger adds up to.
+func (s *PhoneServiceImpl) GetStockMovements(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	var phone models.Phone
+	if err := s.DB.Unscoped().Model(&models.Phone{}).Omit("image").Where("id = ?", c.Params("id")).First(&phone).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "phone not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone error",
+			Error:   err,
+		})
+	}
+
+	queryMovements := s.DB.Model(&models.StockMovement{}).Where("phone_id = ?", phone.ID)
+	if movementType := c.Query("type"); movementType != "" {
+		queryMovements = queryMovements.Where("type = ?", movementType)
+	}
+
+	var total int64
+	if err := queryMovements.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count stock movements error",
+			Error:   err,
+		})
+	}
+
+	var movements []models.StockMovement
+	if err := queryMovements.Order("id DESC").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&movements).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock movements error",
+			Error:   err,
+		})
+	}
+
+	var ledgerAmount int
+	if err := s.DB.Model(&models.StockMovement{}).Select("COALESCE(SUM(quantity), 0)").Where("phone_id = ?", phone.ID).Scan(&ledgerAmount).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database sum stock movements error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
+		Message: "get stock movements success",
+		Data: fiber.Map{
+			"phone_id":      phone.ID,
+			"amount":        phone.Amount,
+			"ledger_amount": ledgerAmount,
+			"movements":     movements,
+		},
+		Total: int(total),
+	})
+}
+
+// AdjustStock corrects the stock of one phone by a signed quantity without touching its other fields.
+func (s *PhoneServiceImpl) AdjustStock(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestAdjustStock)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "locals req error",
+			Error:   nil,
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+			Error:   nil,
+		})
+	}
+
+	var phone models.Phone
+	if err := s.DB.Model(&models.Phone{}).Omit("image").Where("id = ?", c.Params("id")).First(&phone).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "phone not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone error",
+			Error:   err,
+		})
+	}
+
+	movement := models.StockMovement{
+		PhoneID:     phone.ID,
+		Type:        models.StockMovementAdjustment,
+		Quantity:    req.Quantity,
+		Note:        req.Reason,
+		CreatedByID: userIDOrNil(user.ID),
+	}
+
+	tx := s.DB.Begin()
+
+	if err := adjustPhoneStock(tx, &movement); err != nil {
+		tx.Rollback()
+		if err == ErrInsufficientStock {
+			return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("cannot take %d out , only %d in stock", -req.Quantity, phone.Amount),
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "record stock movement error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	queueLowStockCheck(phone.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "adjust stock success",
+		Data:    movement,
+	})
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultStockReservationTTL = 15 * time.Minute
+
+// Stock reservations hold phones in a buyer's cart for a short time so checkout of another buyer
+// cannot take them. Each phone has one redis hash, fields are user ids and values "amount:expires_at_ms".
+
+// reserveStockScript drops expired holds, then sets the hold of ARGV[1] to ARGV[2] if the stock in
+// ARGV[3] minus the holds of other buyers covers it. It returns {1 or 0, stock left for this buyer}.
+var reserveStockScript = redis.NewScript(`
+local now = tonumber(ARGV[4])
+local reservedByOthers = 0
+local entries = redis.call('HGETALL', KEYS[1])
+for i = 1, #entries, 2 do
+	local amount, expiresAt = string.match(entries[i + 1], '(%d+):(%d+)')
+	if tonumber(expiresAt) <= now then
+		redis.call('HDEL', KEYS[1], entries[i])
+	elseif entries[i] ~= ARGV[1] then
+		reservedByOthers = reservedByOthers + tonumber(amount)
+	end
+end
+
+local available = tonumber(ARGV[3]) - reservedByOthers
+if tonumber(ARGV[2]) > available then
+	return {0, available}
+end
+
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2] .. ':' .. tostring(now + tonumber(ARGV[5])))
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return {1, available}
+`)
+
+// getStockReservationTTL reads STOCK_RESERVATION_TTL in minutes, 0 turns reservations off.
+func getStockReservationTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("STOCK_RESERVATION_TTL"))
+	if err != nil || minutes < 0 {
+		return defaultStockReservationTTL
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
+
+func stockReservationKey(phoneID uint) string {
+	return fmt.Sprintf("stock:reservations:%d", phoneID)
+}
+
+// reserveStock holds amount of the phone for the user, replacing the user's previous hold.
+// It reports whether the hold was taken and how many the user could have taken.
+func reserveStock(ctx context.Context, redisClient *redis.Client, phoneID uint, userID uint, amount int, stock int) (bool, int, error) {
+	ttl := getStockReservationTTL()
+	if ttl == 0 {
+		return amount <= stock, stock, nil
+	}
+
+	result, err := reserveStockScript.Run(ctx, redisClient, []string{stockReservationKey(phoneID)},
+		userID, amount, stock, time.Now().UnixMilli(), ttl.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return result[0] == 1, int(result[1]), nil
+}
+
+func releaseStock(ctx context.Context, redisClient *redis.Client, phoneID uint, userID uint) error {
+	return redisClient.HDel(ctx, stockReservationKey(phoneID), strconv.FormatUint(uint64(userID), 10)).Err()
+}
+
+// restoreStockHold puts the user's hold back to what the cart held before a failed write,
+// a new line had no hold so it is released instead of blocking other buyers until it expires.
+func restoreStockHold(ctx context.Context, redisClient *redis.Client, phone models.Phone, userID uint, previousAmount int) {
+	if previousAmount == 0 {
+		if err := releaseStock(ctx, redisClient, phone.ID, userID); err != nil {
+			log.Printf("release stock hold of phone %d failed : %v", phone.ID, err)
+		}
+		return
+	}
+
+	if _, _, err := reserveStock(ctx, redisClient, phone.ID, userID, previousAmount, phone.Amount); err != nil {
+		log.Printf("restore stock hold of phone %d failed : %v", phone.ID, err)
+	}
+}
+
+// getReservedStock sums the live holds per phone, holds of excludeUserID are left out, 0 counts every buyer.
+func getReservedStock(ctx context.Context, redisClient *redis.Client, phoneIDs []uint, excludeUserID uint) (map[uint]int, error) {
+	reserved := make(map[uint]int, len(phoneIDs))
+	if len(phoneIDs) == 0 || getStockReservationTTL() == 0 {
+		return reserved, nil
+	}
+
+	pipe := redisClient.Pipeline()
+	commands := make([]*redis.MapStringStringCmd, len(phoneIDs))
+	for i, phoneID := range phoneIDs {
+		commands[i] = pipe.HGetAll(ctx, stockReservationKey(phoneID))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	excludeField := strconv.FormatUint(uint64(excludeUserID), 10)
+
+	for i, command := range commands {
+		for userField, value := range command.Val() {
+			if excludeUserID != 0 && userField == excludeField {
+				continue
+			}
+
+			rawAmount, rawExpiresAt, ok := strings.Cut(value, ":")
+			if !ok {
+				continue
+			}
+
+			amount, err := strconv.Atoi(rawAmount)
+			if err != nil {
+				continue
+			}
+
+			expiresAt, err := strconv.ParseInt(rawExpiresAt, 10, 64)
+			if err != nil || expiresAt <= now {
+				continue
+			}
+
+			reserved[phoneIDs[i]] += amount
+		}
+	}
+
+	return reserved, nil
+}
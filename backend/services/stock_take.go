@@ -0,0 +1,481 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errStockTakeNotOpen = errors.New("stock take is not open")
+
+type StockTakeServiceImpl struct {
+	DB *gorm.DB
+}
+
+type StockTakeService interface {
+	OpenStockTake(c *fiber.Ctx) error
+	GetStockTakes(c *fiber.Ctx) error
+	GetStockTakeByID(c *fiber.Ctx) error
+	SubmitStockTakeCounts(c *fiber.Ctx) error
+	ScanStockTake(c *fiber.Ctx) error
+	PostStockTake(c *fiber.Ctx) error
+	CancelStockTake(c *fiber.Ctx) error
+}
+
+func NewStockTakeService(configClients configs.ConfigClients) StockTakeService {
+	return &StockTakeServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+func (s *StockTakeServiceImpl) OpenStockTake(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestOpenStockTake)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	stockTake := models.StockTake{
+		Status:     models.StockTakeStatusOpen,
+		Note:       req.Note,
+		OpenedByID: user.ID,
+		Lines:      []models.StockTakeLine{},
+	}
+
+	if err := s.DB.Create(&stockTake).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "create stock take failed",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "open stock take success",
+		Data:    stockTake,
+	})
+}
+
+func (s *StockTakeServiceImpl) GetStockTakes(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	queryStockTakes := s.DB.Model(&models.StockTake{})
+	if status := c.Query("status"); status != "" {
+		queryStockTakes = queryStockTakes.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := queryStockTakes.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count stock takes error",
+			Error:   err,
+		})
+	}
+
+	var stockTakes []models.StockTake
+	if err := queryStockTakes.Order("id DESC").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&stockTakes).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock takes error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
+		Message: "get stock takes success",
+		Data:    stockTakes,
+		Total:   int(total),
+	})
+}
+
+// GetStockTakeByID shows the counted lines with their variance against the stock when each phone was counted.
+func (s *StockTakeServiceImpl) GetStockTakeByID(c *fiber.Ctx) error {
+	stockTake, err := getStockTake(s.DB, c.Params("id"))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "stock take not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock take error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get stock take success",
+		Data:    stockTake,
+	})
+}
+
+func (s *StockTakeServiceImpl) SubmitStockTakeCounts(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestSubmitStockTakeCounts)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	phoneIDs := make([]uint, 0, len(req.Counts))
+	for _, count := range req.Counts {
+		phoneIDs = append(phoneIDs, count.PhoneID)
+	}
+
+	tx := s.DB.Begin()
+
+	stockTake, err := lockStockTake(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handleLockStockTakeError(c, err)
+	}
+
+	var phones []models.Phone
+	if err := tx.Model(&models.Phone{}).Select("id", "amount").Where("id IN ?", phoneIDs).Find(&phones).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phones error",
+			Error:   err,
+		})
+	}
+
+	if len(phones) != countDistinct(phoneIDs) {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "some phones not found",
+			Error:   nil,
+		})
+	}
+
+	expectedAmounts := make(map[uint]int, len(phones))
+	for _, phone := range phones {
+		expectedAmounts[phone.ID] = phone.Amount
+	}
+
+	var countedLines []models.StockTakeLine
+	if err := tx.Model(&models.StockTakeLine{}).Select("phone_id", "expected_amount").Where("stock_take_id = ? AND phone_id IN ?", stockTake.ID, phoneIDs).Find(&countedLines).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock take lines error",
+			Error:   err,
+		})
+	}
+
+	// the stock when a phone was first counted is kept , a recount only replaces the counted amount
+	for _, line := range countedLines {
+		expectedAmounts[line.PhoneID] = line.ExpectedAmount
+	}
+
+	lines := make([]models.StockTakeLine, 0, len(req.Counts))
+	for _, count := range req.Counts {
+		lines = append(lines, models.StockTakeLine{
+			StockTakeID:    stockTake.ID,
+			PhoneID:        count.PhoneID,
+			CountedAmount:  count.CountedAmount,
+			ExpectedAmount: expectedAmounts[count.PhoneID],
+			Variance:       count.CountedAmount - expectedAmounts[count.PhoneID],
+		})
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"counted_amount", "variance", "updated_at"}),
+	}).Create(&lines).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "save stock take counts failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return respondStockTake(c, s.DB, "submit stock take counts success")
+}
+
+// ScanStockTake counts phones by barcode, each scan adds to what was counted before.
+// Like submitted counts, the expected amount is taken when the phone is first counted and kept after.
+func (s *StockTakeServiceImpl) ScanStockTake(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestScanStockTake)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	stockTake, err := lockStockTake(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handleLockStockTakeError(c, err)
+	}
+
+	var phone models.Phone
+	if err := tx.Model(&models.Phone{}).Omit("image").Where("barcode = ?", req.Barcode).First(&phone).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("no phone with barcode %s", req.Barcode),
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone error",
+			Error:   err,
+		})
+	}
+
+	line := models.StockTakeLine{
+		StockTakeID:    stockTake.ID,
+		PhoneID:        phone.ID,
+		CountedAmount:  req.Quantity,
+		ExpectedAmount: phone.Amount,
+		Variance:       req.Quantity - phone.Amount,
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"counted_amount": gorm.Expr("counted_amount + ?", req.Quantity),
+			"variance":       gorm.Expr("variance + ?", req.Quantity),
+			"updated_at":     time.Now(),
+		}),
+	}).Create(&line).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "save stock take scan failed",
+			Error:   err,
+		})
+	}
+
+	if err := tx.Where("stock_take_id = ? AND phone_id = ?", stockTake.ID, phone.ID).First(&line).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock take line error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	line.Phone = phone
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "scan stock take success",
+		Data:    line,
+	})
+}
+
+// PostStockTake applies the variance of every counted phone as stock take movements in one transaction.
+// The variance is against the stock when the phone was counted, so sales and receipts since then are kept.
+// Phones that were not counted are left as they are.
+func (s *StockTakeServiceImpl) PostStockTake(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestPostStockTake)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	stockTake, err := lockStockTake(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handleLockStockTakeError(c, err)
+	}
+
+	var lines []models.StockTakeLine
+	if err := tx.Where("stock_take_id = ?", stockTake.ID).Order("phone_id").Find(&lines).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock take lines error",
+			Error:   err,
+		})
+	}
+
+	if len(lines) == 0 {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "stock take has no counts",
+			Error:   nil,
+		})
+	}
+
+	note := fmt.Sprintf("stock take %d : %s", stockTake.ID, req.Reason)
+	// lines are applied in phone id order so two posts never wait on each other
+	for _, line := range lines {
+		if line.Variance == 0 {
+			continue
+		}
+
+		if err := adjustPhoneStock(tx, &models.StockMovement{
+			PhoneID:     line.PhoneID,
+			Type:        models.StockMovementStockTake,
+			Quantity:    line.Variance,
+			StockTakeID: &stockTake.ID,
+			Note:        note,
+			CreatedByID: userIDOrNil(user.ID),
+		}); err != nil {
+			tx.Rollback()
+			if err == ErrInsufficientStock {
+				return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+					Message: fmt.Sprintf("phone %d has less stock than the count takes out , count it again", line.PhoneID),
+					Error:   err,
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "record stock movement failed",
+				Error:   err,
+			})
+		}
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.StockTake{}).Where("id = ?", stockTake.ID).Updates(map[string]interface{}{
+		"status":       models.StockTakeStatusPosted,
+		"reason":       req.Reason,
+		"posted_by_id": user.ID,
+		"posted_at":    now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update stock take failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	phoneIDs := make([]uint, 0, len(lines))
+	for _, line := range lines {
+		phoneIDs = append(phoneIDs, line.PhoneID)
+	}
+	queueLowStockCheck(phoneIDs...)
+
+	return respondStockTake(c, s.DB, "post stock take success")
+}
+
+func (s *StockTakeServiceImpl) CancelStockTake(c *fiber.Ctx) error {
+	tx := s.DB.Begin()
+
+	stockTake, err := lockStockTake(tx, c.Params("id"))
+	if err != nil {
+		tx.Rollback()
+		return handleLockStockTakeError(c, err)
+	}
+
+	if err := tx.Model(&models.StockTake{}).Where("id = ?", stockTake.ID).Update("status", models.StockTakeStatusCancelled).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update stock take failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "cancel stock take success",
+		Data:    nil,
+	})
+}
+
+// getStockTake loads the stock take with its lines.
+func getStockTake(db *gorm.DB, id string) (models.StockTake, error) {
+	var stockTake models.StockTake
+	if err := db.Where("id = ?", id).Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("phone_id")
+	}).Preload("Lines.Phone", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Omit("image")
+	}).First(&stockTake).Error; err != nil {
+		return stockTake, err
+	}
+
+	return stockTake, nil
+}
+
+func respondStockTake(c *fiber.Ctx, db *gorm.DB, message string) error {
+	stockTake, err := getStockTake(db, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock take error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: message,
+		Data:    stockTake,
+	})
+}
+
+func lockStockTake(tx *gorm.DB, id string) (models.StockTake, error) {
+	var stockTake models.StockTake
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&stockTake).Error; err != nil {
+		return stockTake, err
+	}
+
+	if stockTake.Status != models.StockTakeStatusOpen {
+		return stockTake, errStockTakeNotOpen
+	}
+
+	return stockTake, nil
+}
+
+func handleLockStockTakeError(c *fiber.Ctx, err error) error {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "stock take not found",
+			Error:   err,
+		})
+	case errStockTakeNotOpen:
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: "stock take is already posted or cancelled",
+			Error:   err,
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get stock take error",
+			Error:   err,
+		})
+	}
+}
+
+func countDistinct(ids []uint) int {
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+
+	return len(seen)
+}
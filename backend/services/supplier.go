@@ -0,0 +1,171 @@
+package services
+
+import (
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+)
+
+type SupplierServiceImpl struct {
+	DB *gorm.DB
+}
+
+type SupplierService interface {
+	GetSuppliers(c *fiber.Ctx) error
+	CreateSupplier(c *fiber.Ctx) error
+	UpdateSupplier(c *fiber.Ctx) error
+	DeleteSupplier(c *fiber.Ctx) error
+}
+
+func NewSupplierService(configClients configs.ConfigClients) SupplierService {
+	return &SupplierServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+func (s *SupplierServiceImpl) GetSuppliers(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	querySuppliers := s.DB.Model(&models.Supplier{})
+	if query.Search != "" {
+		querySuppliers = querySuppliers.Where("name LIKE ?", "%"+query.Search+"%")
+	}
+
+	var total int64
+	if err := querySuppliers.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count suppliers error",
+			Error:   err,
+		})
+	}
+
+	var suppliers []models.Supplier
+	if err := querySuppliers.Order("name ASC").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&suppliers).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get suppliers error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
+		Message: "get suppliers success",
+		Data:    suppliers,
+		Total:   int(total),
+	})
+}
+
+func (s *SupplierServiceImpl) CreateSupplier(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestCreateSupplier)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	supplier := models.Supplier{
+		Name:        req.Name,
+		ContactName: req.ContactName,
+		Email:       req.Email,
+		PhoneNumber: req.PhoneNumber,
+		Address:     req.Address,
+		TaxID:       req.TaxID,
+		Active:      true,
+	}
+
+	if err := s.DB.Create(&supplier).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create supplier error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "create supplier success",
+		Data:    supplier,
+	})
+}
+
+func (s *SupplierServiceImpl) UpdateSupplier(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestUpdateSupplier)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.ContactName != "" {
+		updates["contact_name"] = req.ContactName
+	}
+	if req.Email != "" {
+		updates["email"] = req.Email
+	}
+	if req.PhoneNumber != "" {
+		updates["phone_number"] = req.PhoneNumber
+	}
+	if req.Address != "" {
+		updates["address"] = req.Address
+	}
+	if req.TaxID != "" {
+		updates["tax_id"] = req.TaxID
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if len(updates) > 0 {
+		if err := s.DB.Model(&models.Supplier{}).Where("id = ?", c.Params("id")).Updates(updates).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database update supplier error",
+				Error:   err,
+			})
+		}
+	}
+
+	var supplier models.Supplier
+	if err := s.DB.Model(&models.Supplier{}).Where("id = ?", c.Params("id")).First(&supplier).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "supplier not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get supplier error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "update supplier success",
+		Data:    supplier,
+	})
+}
+
+// DeleteSupplier soft deletes so purchase orders already sent to the supplier still show who it was.
+func (s *SupplierServiceImpl) DeleteSupplier(c *fiber.Ctx) error {
+	if err := s.DB.Delete(&models.Supplier{}, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database delete supplier error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "delete supplier success",
+		Data:    nil,
+	})
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-pdf/fpdf"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaxInvoiceServiceImpl struct {
+	DB *gorm.DB
+}
+
+type TaxInvoiceService interface {
+	IssueTaxInvoice(c *fiber.Ctx) error
+	GetTaxInvoicePDF(c *fiber.Ctx) error
+}
+
+func NewTaxInvoiceService(configClients configs.ConfigClients) TaxInvoiceService {
+	return &TaxInvoiceServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+var taxInvoiceStatuses = []string{
+	models.OrderStatusPaid,
+	models.OrderStatusPacking,
+	models.OrderStatusShipped,
+	models.OrderStatusDelivered,
+}
+
+func (s *TaxInvoiceServiceImpl) IssueTaxInvoice(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).Preload("Cart").Preload("Cart.User").First(&order).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if !canViewOrder(user, order) {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	var invoice models.TaxInvoice
+	err := tx.Model(&models.TaxInvoice{}).Where("order_id = ?", order.ID).First(&invoice).Error
+	if err == nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+			Message: "tax invoice already issued",
+			Data:    invoice,
+		})
+	}
+	if err != gorm.ErrRecordNotFound {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get tax invoice error",
+			Error:   err,
+		})
+	}
+
+	if !containsString(taxInvoiceStatuses, order.Status) {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot issue tax invoice for order with status %s", order.Status),
+			Error:   nil,
+		})
+	}
+
+	now := time.Now()
+
+	number, err := nextTaxInvoiceNumber(tx, now.Year())
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "generate tax invoice number failed",
+			Error:   err,
+		})
+	}
+
+	buyer := order.Cart.User
+	invoice = models.TaxInvoice{
+		Number:       number,
+		OrderID:      order.ID,
+		BuyerName:    fmt.Sprintf("%s %s", buyer.FirstName, buyer.LastName),
+		BuyerAddress: buyer.Address,
+		BuyerTaxID:   buyer.TaxID,
+		BuyerBranch:  buyer.TaxBranch,
+		Subtotal:     order.Subtotal,
+		VATRate:      order.VATRate,
+		VATAmount:    order.VATAmount,
+		Total:        order.TotalPrice,
+		IssuedByID:   user.ID,
+		IssuedAt:     now,
+	}
+
+	if err := tx.Create(&invoice).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create tax invoice error",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "issue tax invoice success",
+		Data:    invoice,
+	})
+}
+
+func (s *TaxInvoiceServiceImpl) GetTaxInvoicePDF(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	var order models.Order
+	if err := s.DB.Model(&models.Order{}).Where("id = ?", c.Params("id")).Preload("Cart").Preload("Cart.Items").First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "order not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database error",
+			Error:   err,
+		})
+	}
+
+	if !canViewOrder(user, order) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "order not found",
+			Error:   nil,
+		})
+	}
+
+	var invoice models.TaxInvoice
+	if err := s.DB.Model(&models.TaxInvoice{}).Where("order_id = ?", order.ID).First(&invoice).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "tax invoice not issued",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get tax invoice error",
+			Error:   err,
+		})
+	}
+
+	file, err := renderTaxInvoicePDF(invoice, order, utils.GetShopInfo())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "render tax invoice failed",
+			Error:   err,
+		})
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", invoice.Number+".pdf"))
+
+	return c.Send(file)
+}
+
+// nextTaxInvoiceNumber takes the next number of the year under a row lock on its sequence.
+func nextTaxInvoiceNumber(tx *gorm.DB, year int) (string, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.TaxInvoiceSequence{Year: year}).Error; err != nil {
+		return "", err
+	}
+
+	var sequence models.TaxInvoiceSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("year = ?", year).First(&sequence).Error; err != nil {
+		return "", err
+	}
+
+	sequence.LastNumber++
+
+	if err := tx.Model(&models.TaxInvoiceSequence{}).Where("year = ?", year).Update("last_number", sequence.LastNumber).Error; err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("INV%d-%06d", year, sequence.LastNumber), nil
+}
+
+func renderTaxInvoicePDF(invoice models.TaxInvoice, order models.Order, shop utils.ShopInfo) ([]byte, error) {
+	pdf, font, err := utils.NewPDF()
+	if err != nil {
+		return nil, err
+	}
+
+	pdf.AddPage()
+
+	pdf.SetFont(font, "B", 16)
+	pdf.CellFormat(0, 8, utils.PDFLabel("ใบกำกับภาษี", "TAX INVOICE"), "", 1, "C", false, 0, "")
+	pdf.Ln(2)
+
+	writePDFShopHeader(pdf, font, shop)
+
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("เลขที่", "No."), invoice.Number), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("วันที่", "Date"), utils.ParseToLocalTime(invoice.IssuedAt).Format("02/01/2006")), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	pdf.SetFont(font, "B", 10)
+	pdf.CellFormat(0, 5, utils.PDFLabel("ผู้ซื้อ", "Buyer"), "", 1, "L", false, 0, "")
+	pdf.SetFont(font, "", 10)
+	pdf.CellFormat(0, 5, invoice.BuyerName, "", 1, "L", false, 0, "")
+	pdf.MultiCell(0, 5, invoice.BuyerAddress, "", "L", false)
+	if invoice.BuyerTaxID != "" {
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s  %s: %s", utils.PDFLabel("เลขประจำตัวผู้เสียภาษี", "Tax ID"), invoice.BuyerTaxID, utils.PDFLabel("สาขา", "Branch"), invoice.BuyerBranch), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	writePDFItemTable(pdf, font, order.Cart.Items, order.ShippingFee)
+
+	writePDFTotals(pdf, font, invoice.Subtotal, invoice.VATRate, order.VATInclusive, invoice.VATAmount, invoice.Total)
+
+	return utils.OutputPDF(pdf)
+}
+
+func writePDFShopHeader(pdf *fpdf.Fpdf, font string, shop utils.ShopInfo) {
+	pdf.SetFont(font, "B", 11)
+	pdf.CellFormat(0, 6, shop.Name, "", 1, "L", false, 0, "")
+	pdf.SetFont(font, "", 10)
+	if shop.Address != "" {
+		pdf.MultiCell(0, 5, shop.Address, "", "L", false)
+	}
+	if shop.Phone != "" {
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s", utils.PDFLabel("โทร", "Tel"), shop.Phone), "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(0, 5, fmt.Sprintf("%s: %s  %s: %s", utils.PDFLabel("เลขประจำตัวผู้เสียภาษี", "Tax ID"), shop.TaxID, utils.PDFLabel("สาขา", "Branch"), shop.Branch), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+}
+
+// writePDFItemTable lists items from their snapshot so documents keep the price the buyer paid,
+// the shipping fee gets its own line when the order was charged one.
+func writePDFItemTable(pdf *fpdf.Fpdf, font string, items []models.Item, shippingFee models.Money) {
+	rows := make([][]string, 0, len(items))
+	for i, item := range items {
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", i+1),
+			fmt.Sprintf("%s %s", item.BrandName, item.ModelName),
+			fmt.Sprintf("%d", item.Amount),
+			item.UnitPrice.String(),
+			item.UnitPrice.Mul(item.Amount).String(),
+		})
+	}
+
+	if shippingFee > 0 {
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", len(items)+1),
+			utils.PDFLabel("ค่าจัดส่ง", "Shipping"),
+			"1",
+			shippingFee.String(),
+			shippingFee.String(),
+		})
+	}
+
+	utils.WritePDFTable(pdf, font,
+		[]string{"#", utils.PDFLabel("รายการ", "Description"), utils.PDFLabel("จำนวน", "Qty"), utils.PDFLabel("ราคา", "Unit price"), utils.PDFLabel("รวม", "Amount")},
+		[]float64{10, 80, 25, 32.5, 32.5},
+		[]string{"C", "L", "R", "R", "R"},
+		rows,
+	)
+	pdf.Ln(3)
+}
+
+func writePDFTotals(pdf *fpdf.Fpdf, font string, subtotal models.Money, vatRate models.Rate, vatInclusive bool, vatAmount models.Money, total models.Money) {
+	vatLabel := fmt.Sprintf("%s %s%%", utils.PDFLabel("ภาษีมูลค่าเพิ่ม", "VAT"), vatRate.String())
+	if vatInclusive {
+		vatLabel += fmt.Sprintf(" (%s)", utils.PDFLabel("รวมในราคาแล้ว", "included"))
+	}
+
+	writePDFTotalLine(pdf, font, "", utils.PDFLabel("มูลค่าก่อนภาษี", "Subtotal"), subtotal)
+	writePDFTotalLine(pdf, font, "", vatLabel, vatAmount)
+	writePDFTotalLine(pdf, font, "B", utils.PDFLabel("รวมทั้งสิ้น", "Total"), total)
+}
+
+func writePDFTotalLine(pdf *fpdf.Fpdf, font string, style string, label string, amount models.Money) {
+	pdf.SetFont(font, style, 10)
+	pdf.CellFormat(147.5, 6, label, "", 0, "R", false, 0, "")
+	pdf.CellFormat(32.5, 6, amount.String(), "", 1, "R", false, 0, "")
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+
+	return false
+}
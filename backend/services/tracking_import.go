@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"mime/multipart"
+	"strconv"
+	"strings"
+
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TrackingImportApplied = "APPLIED"
+	TrackingImportFailed  = "FAILED"
+)
+
+type TrackingImportResult struct {
+	Row            int    `json:"row"`
+	OrderID        uint   `json:"order_id"`
+	TrackingNumber string `json:"tracking_number"`
+	Carrier        string `json:"carrier"`
+	Status         string `json:"status"` // APPLIED , FAILED
+	Error          string `json:"error,omitempty"`
+}
+
+// ImportTrackingNumbers ships every valid row of the uploaded sheet in one transaction.
+// Columns are order_id, tracking_number and an optional carrier code, a header row is skipped.
+func (s *OrderServiceImpl) ImportTrackingNumbers(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	file, ok := c.Locals("file").(*multipart.FileHeader)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "locals file error",
+			Error:   nil,
+		})
+	}
+
+	rows, err := utils.ReadSpreadsheetRows(file)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "read import file failed",
+			Error:   err,
+		})
+	}
+
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		if _, err := strconv.ParseUint(rows[0][0], 10, 64); err != nil {
+			rows[0] = nil
+		}
+	}
+
+	tx := s.DB.Begin()
+
+	results := make([]TrackingImportResult, 0, len(rows))
+	// only applied rows are recorded , a row that failed does not block a later fix of it
+	rowByTrackingNumber := make(map[string]int, len(rows))
+	rowByOrderID := make(map[uint]int, len(rows))
+	applied := 0
+
+	for i, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+
+		result := TrackingImportResult{
+			Row:    i + 1,
+			Status: TrackingImportFailed,
+		}
+
+		orderID, err := strconv.ParseUint(cellAt(row, 0), 10, 64)
+		if err != nil || orderID == 0 {
+			result.Error = "order_id invalid"
+			results = append(results, result)
+			continue
+		}
+
+		result.OrderID = uint(orderID)
+		result.TrackingNumber = cellAt(row, 1)
+		result.Carrier = cellAt(row, 2)
+
+		if result.TrackingNumber == "" || len(result.TrackingNumber) > 50 {
+			result.Error = "tracking_number is required and at most 50 characters"
+			results = append(results, result)
+			continue
+		}
+
+		if previousRow, ok := rowByOrderID[result.OrderID]; ok {
+			result.Error = fmt.Sprintf("order already listed on row %d", previousRow)
+			results = append(results, result)
+			continue
+		}
+
+		// the unique index on tracking numbers ignores case
+		trackingKey := strings.ToUpper(result.TrackingNumber)
+		if previousRow, ok := rowByTrackingNumber[trackingKey]; ok {
+			result.Error = fmt.Sprintf("tracking number already listed on row %d", previousRow)
+			results = append(results, result)
+			continue
+		}
+
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", result.OrderID).First(&order).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				result.Error = "order not found"
+				results = append(results, result)
+				continue
+			}
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database error",
+				Error:   err,
+			})
+		}
+
+		if order.Status == models.OrderStatusShipped || order.Status == models.OrderStatusDelivered {
+			result.Error = "order already shipped"
+			results = append(results, result)
+			continue
+		}
+
+		if err := shipOrder(tx, s.Carriers, &order, result.TrackingNumber, result.Carrier, user.ID); err != nil {
+			switch err {
+			case ErrOrderStatusTransition:
+				result.Error = fmt.Sprintf("order must be packing before it can be shipped, status is %s", order.Status)
+			case ErrTrackingNumberInvalid:
+				result.Error = fmt.Sprintf("tracking number is not a valid %s tracking number", order.ShippingCarrier)
+			case ErrTrackingNumberInUse:
+				result.Error = err.Error()
+			default:
+				tx.Rollback()
+				return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+					Message: "update tracking number failed",
+					Error:   err,
+				})
+			}
+			results = append(results, result)
+			continue
+		}
+
+		rowByOrderID[result.OrderID] = result.Row
+		rowByTrackingNumber[trackingKey] = result.Row
+
+		result.Carrier = order.ShippingCarrier
+		result.Status = TrackingImportApplied
+		results = append(results, result)
+		applied++
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "import tracking numbers success",
+		Data: fiber.Map{
+			"applied": applied,
+			"failed":  len(results) - applied,
+			"results": results,
+		},
+	})
+}
+
+func cellAt(row []string, index int) string {
+	if index >= len(row) {
+		return ""
+	}
+
+	return row[index]
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if cell != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -275,6 +275,8 @@ func (s *UserServiceImpl) UpdateUser(c *fiber.Ctx) error {
 	user.Address = req.Address
 	user.Age = req.Age
 	user.BirthDate = req.BirthDate
+	user.TaxID = req.TaxID
+	user.TaxBranch = req.TaxBranch
 
 	if err := s.DB.Save(&user).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
@@ -0,0 +1,489 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BaimhonS/kab-phone/configs"
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/BaimhonS/kab-phone/validates"
+	"github.com/gofiber/fiber/v2"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WarrantyServiceImpl struct {
+	DB *gorm.DB
+}
+
+type WarrantyService interface {
+	SetWarrantyTerms(c *fiber.Ctx) error
+	GetWarranties(c *fiber.Ctx) error
+	CreateWarrantyClaim(c *fiber.Ctx) error
+	GetWarrantyClaims(c *fiber.Ctx) error
+	GetWarrantyClaimByID(c *fiber.Ctx) error
+	UpdateWarrantyClaimStatus(c *fiber.Ctx) error
+}
+
+func NewWarrantyService(configClients configs.ConfigClients) WarrantyService {
+	return &WarrantyServiceImpl{
+		DB: configClients.DB,
+	}
+}
+
+// SetWarrantyTerms replaces the warranty terms of a phone, warranties already given out keep their terms.
+func (s *WarrantyServiceImpl) SetWarrantyTerms(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestSetWarrantyTerms)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	var phone models.Phone
+	if err := s.DB.Model(&models.Phone{}).Omit("image").Where("id = ?", c.Params("id")).First(&phone).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "phone not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get phone error",
+			Error:   err,
+		})
+	}
+
+	terms := make([]models.WarrantyTerm, 0, len(req.Terms))
+	for _, term := range req.Terms {
+		terms = append(terms, models.WarrantyTerm{
+			PhoneID: phone.ID,
+			Type:    term.Type,
+			Months:  term.Months,
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	if err := tx.Where("phone_id = ?", phone.ID).Delete(&models.WarrantyTerm{}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database delete warranty terms error",
+			Error:   err,
+		})
+	}
+
+	if len(terms) > 0 {
+		if err := tx.Create(&terms).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database create warranty terms error",
+				Error:   err,
+			})
+		}
+	}
+
+	tx.Commit()
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "set warranty terms success",
+		Data:    terms,
+	})
+}
+
+// GetWarranties lists the user's warranties that are still running, include_expired=true lists all of them.
+// Admins can look at one customer with user_id.
+func (s *WarrantyServiceImpl) GetWarranties(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	queryWarranties := s.DB.Model(&models.Warranty{})
+	if !isAdmin(user) {
+		queryWarranties = queryWarranties.Where("user_id = ?", user.ID)
+	} else if userID := c.Query("user_id"); userID != "" {
+		queryWarranties = queryWarranties.Where("user_id = ?", userID)
+	}
+
+	if c.Query("include_expired") != "true" {
+		queryWarranties = queryWarranties.Where("expires_at > ?", time.Now())
+	}
+
+	var warranties []models.Warranty
+	if err := queryWarranties.Order("expires_at ASC").Find(&warranties).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get warranties error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get warranties success",
+		Data:    warranties,
+	})
+}
+
+func (s *WarrantyServiceImpl) CreateWarrantyClaim(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestCreateWarrantyClaim)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	var warranty models.Warranty
+	if err := s.DB.Model(&models.Warranty{}).Where("id = ?", c.Params("id")).First(&warranty).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get warranty error",
+			Error:   err,
+		})
+	}
+
+	if warranty.ID == 0 || (!isAdmin(user) && warranty.UserID != user.ID) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "warranty not found",
+			Error:   nil,
+		})
+	}
+
+	if !warranty.IsActive(time.Now()) {
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("warranty expired on %s", warranty.ExpiresAt.Format("2006-01-02")),
+			Error:   nil,
+		})
+	}
+
+	if req.IMEI != "" {
+		var count int64
+		if err := s.DB.Model(&models.PhoneUnit{}).Where("imei = ? AND item_id = ? AND status = ?", req.IMEI, warranty.ItemID, models.PhoneUnitStatusSold).Count(&count).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+				Message: "database get phone unit error",
+				Error:   err,
+			})
+		}
+
+		if count == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "imei was not sold under this warranty",
+				Error:   nil,
+			})
+		}
+	}
+
+	claim := models.WarrantyClaim{
+		WarrantyID: warranty.ID,
+		UserID:     warranty.UserID,
+		IMEI:       req.IMEI,
+		Problem:    req.Problem,
+		Status:     models.WarrantyClaimStatusSubmitted,
+		StatusHistories: []models.WarrantyClaimStatusHistory{{
+			ToStatus:    models.WarrantyClaimStatusSubmitted,
+			ChangedByID: &user.ID,
+		}},
+	}
+
+	if err := s.DB.Create(&claim).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database create warranty claim error",
+			Error:   err,
+		})
+	}
+
+	claim.Warranty = warranty
+
+	return c.Status(fiber.StatusCreated).JSON(utils.SuccessResponse{
+		Message: "create warranty claim success",
+		Data:    claim,
+	})
+}
+
+func (s *WarrantyServiceImpl) GetWarrantyClaims(c *fiber.Ctx) error {
+	var query utils.QueryPagination
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "query parser error",
+			Error:   err,
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	queryClaims := s.DB.Model(&models.WarrantyClaim{})
+	if !isAdmin(user) {
+		queryClaims = queryClaims.Where("user_id = ?", user.ID)
+	}
+	if status := c.Query("status"); status != "" {
+		queryClaims = queryClaims.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := queryClaims.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database count warranty claims error",
+			Error:   err,
+		})
+	}
+
+	var claims []models.WarrantyClaim
+	if err := queryClaims.Preload("Warranty").Order("id DESC").Offset(query.Page * query.PageSize).Limit(query.PageSize).Find(&claims).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get warranty claims error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessPaginationResponse{
+		Message: "get warranty claims success",
+		Data:    claims,
+		Total:   int(total),
+	})
+}
+
+func (s *WarrantyServiceImpl) GetWarrantyClaimByID(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	claim, err := getWarrantyClaim(s.DB, c.Params("id"))
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get warranty claim error",
+			Error:   err,
+		})
+	}
+
+	if err == gorm.ErrRecordNotFound || (!isAdmin(user) && claim.UserID != user.ID) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+			Message: "warranty claim not found",
+			Error:   nil,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "get warranty claim success",
+		Data:    claim,
+	})
+}
+
+func (s *WarrantyServiceImpl) UpdateWarrantyClaimStatus(c *fiber.Ctx) error {
+	req, ok := c.Locals("req").(validates.RequestUpdateWarrantyClaimStatus)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local req not found",
+		})
+	}
+
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "local user not found",
+		})
+	}
+
+	tx := s.DB.Begin()
+
+	var claim models.WarrantyClaim
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", c.Params("id")).First(&claim).Error; err != nil {
+		tx.Rollback()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ErrorResponse{
+				Message: "warranty claim not found",
+				Error:   err,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get warranty claim error",
+			Error:   err,
+		})
+	}
+
+	if !claim.CanTransitionTo(req.Status) {
+		tx.Rollback()
+		return c.Status(fiber.StatusConflict).JSON(utils.ErrorResponse{
+			Message: fmt.Sprintf("cannot change warranty claim status from %s to %s", claim.Status, req.Status),
+			Error:   nil,
+		})
+	}
+
+	updates := map[string]interface{}{
+		"status": req.Status,
+	}
+	if req.ServiceCenter != "" {
+		updates["service_center"] = req.ServiceCenter
+	}
+
+	if err := tx.Model(&models.WarrantyClaim{}).Where("id = ?", claim.ID).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "update warranty claim status failed",
+			Error:   err,
+		})
+	}
+
+	if err := tx.Create(&models.WarrantyClaimStatusHistory{
+		WarrantyClaimID: claim.ID,
+		FromStatus:      claim.Status,
+		ToStatus:        req.Status,
+		Note:            req.Note,
+		ChangedByID:     &user.ID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "create warranty claim status history failed",
+			Error:   err,
+		})
+	}
+
+	tx.Commit()
+
+	claim, err := getWarrantyClaim(s.DB, c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ErrorResponse{
+			Message: "database get warranty claim error",
+			Error:   err,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.SuccessResponse{
+		Message: "update warranty claim status success",
+		Data:    claim,
+	})
+}
+
+func getWarrantyClaim(db *gorm.DB, id string) (models.WarrantyClaim, error) {
+	var claim models.WarrantyClaim
+	err := db.Where("id = ?", id).Preload("Warranty").Preload("StatusHistories", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&claim).Error
+
+	return claim, err
+}
+
+// createOrderWarranties gives the buyer one warranty per term of each delivered order line, starting now.
+// It is safe to run twice for an order, lines that already have a warranty of the type are skipped.
+func createOrderWarranties(tx *gorm.DB, order *models.Order) error {
+	var cart models.Cart
+	if err := tx.Unscoped().Where("id = ?", order.CartID).Preload("Items").First(&cart).Error; err != nil {
+		return err
+	}
+
+	phoneIDs := make([]uint, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		phoneIDs = append(phoneIDs, item.PhoneID)
+	}
+
+	var terms []models.WarrantyTerm
+	if err := tx.Where("phone_id IN ?", phoneIDs).Find(&terms).Error; err != nil {
+		return err
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	refunded, err := refundedItemQuantities(tx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	warranties := make([]models.Warranty, 0, len(terms))
+	for _, item := range cart.Items {
+		quantity := item.Amount - refunded[item.ID]
+		if quantity <= 0 {
+			continue
+		}
+
+		for _, term := range terms {
+			if term.PhoneID != item.PhoneID {
+				continue
+			}
+
+			warranties = append(warranties, models.Warranty{
+				UserID:    cart.UserId,
+				OrderID:   order.ID,
+				ItemID:    item.ID,
+				Type:      term.Type,
+				PhoneID:   item.PhoneID,
+				BrandName: item.BrandName,
+				ModelName: item.ModelName,
+				Quantity:  quantity,
+				Months:    term.Months,
+				StartsAt:  now,
+				ExpiresAt: now.AddDate(0, term.Months, 0),
+			})
+		}
+	}
+
+	if len(warranties) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&warranties).Error
+}
+
+// refundedItemQuantities sums the handsets of each order line that came back through a completed refund return.
+func refundedItemQuantities(tx *gorm.DB, orderID uint) (map[uint]int, error) {
+	var rows []struct {
+		ItemID   uint
+		Quantity int
+	}
+	if err := tx.Model(&models.Return{}).
+		Select("item_id, SUM(quantity) AS quantity").
+		Where("order_id = ? AND status = ? AND resolution = ?", orderID, models.ReturnStatusCompleted, models.ReturnResolutionRefund).
+		Group("item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	quantities := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		quantities[row.ItemID] = row.Quantity
+	}
+
+	return quantities, nil
+}
+
+// releaseReturnedWarranties takes the refunded handsets off the warranties of an order line,
+// a warranty left covering nothing ends now.
+func releaseReturnedWarranties(tx *gorm.DB, itemID uint, quantity int) error {
+	now := time.Now()
+
+	if err := tx.Model(&models.Warranty{}).Where("item_id = ? AND expires_at > ?", itemID, now).
+		Update("quantity", gorm.Expr("quantity - ?", quantity)).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Warranty{}).Where("item_id = ? AND quantity <= 0 AND expires_at > ?", itemID, now).
+		Updates(map[string]interface{}{
+			"quantity":   0,
+			"expires_at": now,
+		}).Error
+}
+
+// endOrderWarranties ends every running warranty of a refunded order now.
+func endOrderWarranties(tx *gorm.DB, orderID uint) error {
+	now := time.Now()
+
+	return tx.Model(&models.Warranty{}).Where("order_id = ? AND expires_at > ?", orderID, now).Update("expires_at", now).Error
+}
@@ -0,0 +1,14 @@
+package utils
+
+import "os"
+
+// IsDevelopment reports whether APP_ENV is development or test, sandbox payment and carrier
+// adapters are only registered there so they can never move real orders.
+func IsDevelopment() bool {
+	switch os.Getenv("APP_ENV") {
+	case "development", "test":
+		return true
+	}
+
+	return false
+}
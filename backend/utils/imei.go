@@ -0,0 +1,27 @@
+package utils
+
+// ValidateIMEI checks a 15 digit IMEI against its Luhn check digit.
+func ValidateIMEI(imei string) bool {
+	if len(imei) != 15 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < len(imei); i++ {
+		digit := int(imei[i] - '0')
+		if digit < 0 || digit > 9 {
+			return false
+		}
+
+		// every second digit from the left is doubled , the check digit is the last one
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+
+	return sum%10 == 0
+}
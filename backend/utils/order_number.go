@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// crockfordAlphabet leaves out I, L, O and U so order numbers read back over the phone without mistakes.
+// Check symbols come from the same alphabet so order numbers never need escaping in a URL.
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const orderNumberRandomLength = 7
+
+var orderNumberPattern = regexp.MustCompile(`^KAB-(\d{4})-([0-9A-HJKMNP-TV-Z]{7})([0-9A-HJKMNP-TV-Z])$`)
+
+// GenerateOrderNumber returns a number like KAB-2026-7K3M9QX4, seven random symbols
+// followed by a Luhn mod 32 check symbol so mistyped numbers are caught before a lookup.
+func GenerateOrderNumber(year int) (string, error) {
+	var body strings.Builder
+	for i := 0; i < orderNumberRandomLength; i++ {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(crockfordAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		body.WriteByte(crockfordAlphabet[index.Int64()])
+	}
+
+	return fmt.Sprintf("KAB-%04d-%s%c", year, body.String(), orderNumberCheckSymbol(body.String())), nil
+}
+
+func ValidateOrderNumber(orderNumber string) bool {
+	match := orderNumberPattern.FindStringSubmatch(strings.ToUpper(orderNumber))
+	if match == nil {
+		return false
+	}
+
+	return orderNumberCheckSymbol(match[2]) == match[3][0]
+}
+
+// orderNumberCheckSymbol is the Luhn mod N check symbol of body over the Crockford alphabet,
+// it catches every single mistyped symbol and most swaps of neighbouring symbols.
+func orderNumberCheckSymbol(body string) byte {
+	base := len(crockfordAlphabet)
+	factor := 2
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(crockfordAlphabet, body[i])
+		sum += addend/base + addend%base
+		factor = 3 - factor
+	}
+
+	return crockfordAlphabet[(base-sum%base)%base]
+}
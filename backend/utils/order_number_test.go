@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGenerateOrderNumberIsURLSafeAndValid(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		orderNumber, err := GenerateOrderNumber(2026)
+		if err != nil {
+			t.Fatalf("generate order number : %v", err)
+		}
+
+		if !strings.HasPrefix(orderNumber, "KAB-2026-") || len(orderNumber) != len("KAB-2026-")+orderNumberRandomLength+1 {
+			t.Fatalf("order number %s has the wrong shape", orderNumber)
+		}
+
+		if escaped := url.PathEscape(orderNumber); escaped != orderNumber {
+			t.Fatalf("order number %s is escaped to %s in a path", orderNumber, escaped)
+		}
+
+		if !ValidateOrderNumber(orderNumber) || !ValidateOrderNumber(strings.ToLower(orderNumber)) {
+			t.Fatalf("order number %s does not validate", orderNumber)
+		}
+	}
+}
+
+func TestValidateOrderNumberCatchesSingleSymbolMistakes(t *testing.T) {
+	orderNumber, err := GenerateOrderNumber(2026)
+	if err != nil {
+		t.Fatalf("generate order number : %v", err)
+	}
+
+	prefix := len("KAB-2026-")
+	for position := prefix; position < len(orderNumber); position++ {
+		for _, symbol := range crockfordAlphabet {
+			if byte(symbol) == orderNumber[position] {
+				continue
+			}
+
+			mistyped := orderNumber[:position] + string(symbol) + orderNumber[position+1:]
+			if ValidateOrderNumber(mistyped) {
+				t.Errorf("%s validates after mistyping %s", mistyped, orderNumber)
+			}
+		}
+	}
+}
+
+func TestValidateOrderNumberRejectsSymbolsOutsideTheAlphabet(t *testing.T) {
+	for _, orderNumber := range []string{
+		"KAB-2026-0000000*",
+		"KAB-2026-0000000~",
+		"KAB-2026-000000U0",
+		"KAB-26-00000000",
+		"",
+	} {
+		if ValidateOrderNumber(orderNumber) {
+			t.Errorf("%q validates", orderNumber)
+		}
+	}
+}
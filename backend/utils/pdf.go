@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/BaimhonS/kab-phone/data"
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfFontPath     = "fonts/Sarabun-Regular.ttf"
+	pdfBoldFontPath = "fonts/Sarabun-Bold.ttf"
+	pdfFontFamily   = "Thai"
+)
+
+// NewPDF creates an A4 document and returns the font family to write with.
+// Thai text needs the unicode font embedded from data/fonts, documents are not rendered without it
+// because a latin fallback would print buyer names and addresses as garbage.
+// The bold face is optional, headings use the regular font when it is missing.
+func NewPDF() (*fpdf.Fpdf, string, error) {
+	regular, err := data.Fonts.ReadFile(pdfFontPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("load pdf font : %w", err)
+	}
+
+	bold, err := data.Fonts.ReadFile(pdfBoldFontPath)
+	if err != nil {
+		bold = regular
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "", regular)
+	pdf.AddUTF8FontFromBytes(pdfFontFamily, "B", bold)
+	if err := pdf.Error(); err != nil {
+		return nil, "", fmt.Errorf("load pdf font %s : %w", pdfFontPath, err)
+	}
+
+	return pdf, pdfFontFamily, nil
+}
+
+// WritePDFTable draws a bordered table, aligns holds fpdf cell alignments per column.
+func WritePDFTable(pdf *fpdf.Fpdf, font string, headers []string, widths []float64, aligns []string, rows [][]string) {
+	pdf.SetFont(font, "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	for i, header := range headers {
+		pdf.CellFormat(widths[i], 8, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(font, "", 10)
+	for _, row := range rows {
+		for i, value := range row {
+			pdf.CellFormat(widths[i], 8, value, "1", 0, aligns[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+// PDFLabel prints a label in Thai and English.
+func PDFLabel(thai string, english string) string {
+	return thai + " / " + english
+}
+
+func OutputPDF(pdf *fpdf.Fpdf) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := pdf.Output(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
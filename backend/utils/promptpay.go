@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+const (
+	promptPayAID          = "A000000677010111"
+	promptPayCountryCode  = "TH"
+	promptPayCurrencyBaht = "764"
+)
+
+var (
+	nonDigit = regexp.MustCompile(`\D`)
+
+	ErrPromptPayIDInvalid = errors.New("promptpay id must be a 10 digit mobile number , a 13 digit national or tax id or a 15 digit e-wallet id")
+)
+
+// NormalizePromptPayID strips the formatting from target and turns a +66 mobile number into its 0 form.
+// Anything that is not a mobile number, a national / tax ID or an e-wallet ID fails with ErrPromptPayIDInvalid.
+func NormalizePromptPayID(target string) (string, error) {
+	target = nonDigit.ReplaceAllString(target, "")
+	if len(target) == 11 && strings.HasPrefix(target, "66") {
+		target = "0" + strings.TrimPrefix(target, "66")
+	}
+
+	switch len(target) {
+	case 10:
+		if !strings.HasPrefix(target, "0") {
+			return "", ErrPromptPayIDInvalid
+		}
+	case 13, 15:
+	default:
+		return "", ErrPromptPayIDInvalid
+	}
+
+	return target, nil
+}
+
+// GeneratePromptPayPayload builds the EMVCo QR payload for a PromptPay transfer to target,
+// which may be a mobile number, a national / tax ID or an e-wallet ID.
+// A positive amount produces a dynamic QR locked to that amount.
+func GeneratePromptPayPayload(target string, amount models.Money) (string, error) {
+	target, err := NormalizePromptPayID(target)
+	if err != nil {
+		return "", err
+	}
+
+	var account string
+	switch len(target) {
+	case 15:
+		account = emvField("03", target)
+	case 13:
+		account = emvField("02", target)
+	default:
+		account = emvField("01", formatPromptPayPhone(target))
+	}
+
+	pointOfInitiation := "11"
+	if amount > 0 {
+		pointOfInitiation = "12"
+	}
+
+	var payload strings.Builder
+	payload.WriteString(emvField("00", "01"))
+	payload.WriteString(emvField("01", pointOfInitiation))
+	payload.WriteString(emvField("29", emvField("00", promptPayAID)+account))
+	payload.WriteString(emvField("58", promptPayCountryCode))
+	payload.WriteString(emvField("53", promptPayCurrencyBaht))
+	if amount > 0 {
+		payload.WriteString(emvField("54", amount.String()))
+	}
+	payload.WriteString("6304")
+
+	return payload.String() + CRC16CCITT(payload.String()), nil
+}
+
+// CRC16CCITT returns the CRC-16/CCITT-FALSE checksum of data as four uppercase hex digits.
+func CRC16CCITT(data string) string {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return fmt.Sprintf("%04X", crc)
+}
+
+func emvField(id string, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// formatPromptPayPhone turns a normalized 0 mobile number into the 13 digit 0066 form PromptPay expects.
+func formatPromptPayPhone(phone string) string {
+	return "0066" + strings.TrimPrefix(phone, "0")
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+func TestCRC16CCITTCheckValue(t *testing.T) {
+	if crc := CRC16CCITT("123456789"); crc != "29B1" {
+		t.Errorf("crc of the check string is %s , want 29B1", crc)
+	}
+}
+
+func TestGeneratePromptPayPayloadKnownGood(t *testing.T) {
+	// the amount QR of the PromptPay reference implementation , crc E469
+	const want = "00020101021229370016A000000677010111011300660000000005802TH530376454044.226304E469"
+
+	payload, err := GeneratePromptPayPayload("000-000-0000", models.Money(422))
+	if err != nil {
+		t.Fatalf("generate payload : %v", err)
+	}
+
+	if payload != want {
+		t.Errorf("payload is %s , want %s", payload, want)
+	}
+}
+
+func TestGeneratePromptPayPayloadTargets(t *testing.T) {
+	mobile, err := GeneratePromptPayPayload("081-234-5678", 0)
+	if err != nil {
+		t.Fatalf("generate mobile payload : %v", err)
+	}
+
+	for _, target := range []string{"+66 81 234 5678", "66812345678"} {
+		payload, err := GeneratePromptPayPayload(target, 0)
+		if err != nil {
+			t.Fatalf("generate payload for %s : %v", target, err)
+		}
+
+		if payload != mobile {
+			t.Errorf("payload for %s is %s , want the mobile payload %s", target, payload, mobile)
+		}
+	}
+
+	tests := []struct {
+		target  string
+		account string
+	}{
+		{"081-234-5678", "29370016A00000067701011101130066812345678"},
+		{"1-2345-67890-12-1", "29370016A00000067701011102131234567890121"},
+		{"123456789012345", "29390016A0000006770101110315123456789012345"},
+	}
+
+	for _, test := range tests {
+		payload, err := GeneratePromptPayPayload(test.target, 0)
+		if err != nil {
+			t.Fatalf("generate payload for %s : %v", test.target, err)
+		}
+
+		if !strings.HasPrefix(payload, "000201010211"+test.account+"5802TH") {
+			t.Errorf("payload for %s is %s , want account %s", test.target, payload, test.account)
+		}
+	}
+}
+
+func TestGeneratePromptPayPayloadRejectsMalformedTargets(t *testing.T) {
+	for _, target := range []string{"", "812345678", "812345678901", "123456789012", "12345678901234", "1234567890123456"} {
+		if _, err := GeneratePromptPayPayload(target, models.Money(100)); err != ErrPromptPayIDInvalid {
+			t.Errorf("target %q : error %v , want %v", target, err, ErrPromptPayIDInvalid)
+		}
+	}
+}
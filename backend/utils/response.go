@@ -26,6 +26,20 @@ type (
 		Error   []*ValidateError `json:"error"`
 	}
 
+	OutOfStockErrorResponse struct {
+		Message string             `json:"message"`
+		Error   []*OutOfStockError `json:"error"`
+	}
+
+	OutOfStockError struct {
+		ItemID    uint   `json:"item_id"`
+		PhoneID   uint   `json:"phone_id"`
+		BrandName string `json:"brand_name"`
+		ModelName string `json:"model_name"`
+		Amount    int    `json:"amount"`
+		Available int    `json:"available"`
+	}
+
 	ValidateError struct {
 		Field string `json:"field"`
 		Tag   string `json:"tag"`
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+// volumetricDivisor turns cubic millimetres into grams the way carriers do (cm³ / 5000 in kg).
+const volumetricDivisor = 5000
+
+var bangkokProvinceNames = map[string]bool{
+	"bangkok":    true,
+	"krung thep": true,
+	"กรุงเทพ":    true,
+	"กรุงเทพฯ":   true,
+	"กรุงเทพมหานคร": true,
+	"กทม":  true,
+	"กทม.": true,
+}
+
+// GetShippingZone maps a province name to the rate table zone, anything outside Bangkok is PROVINCE.
+func GetShippingZone(province string) string {
+	if bangkokProvinceNames[strings.ToLower(strings.TrimSpace(province))] {
+		return models.ShippingZoneBangkok
+	}
+
+	return models.ShippingZoneProvince
+}
+
+// ChargeableWeight is the greater of the actual weight and the volumetric weight of one phone in grams.
+func ChargeableWeight(phone models.Phone) int {
+	volumetric := phone.Width * phone.Height * phone.Depth / volumetricDivisor
+	if volumetric > phone.Weight {
+		return volumetric
+	}
+
+	return phone.Weight
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "os"
+
+type ShopInfo struct {
+	Name    string
+	Address string
+	TaxID   string
+	Branch  string
+	Phone   string
+}
+
+func GetShopInfo() ShopInfo {
+	return ShopInfo{
+		Name:    getEnvOrDefault("SHOP_NAME", "Kab Phone"),
+		Address: os.Getenv("SHOP_ADDRESS"),
+		TaxID:   os.Getenv("SHOP_TAX_ID"),
+		Branch:  getEnvOrDefault("SHOP_BRANCH", "00000"),
+		Phone:   os.Getenv("SHOP_PHONE"),
+	}
+}
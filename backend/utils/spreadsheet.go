@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ReadSpreadsheetRows reads every row of a .csv file or of the first sheet of a .xlsx file,
+// cells are trimmed and rows that are completely empty are kept so row numbers match the file.
+func ReadSpreadsheetRows(fileHeader *multipart.FileHeader) ([][]string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".csv":
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		rows, err = reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+	case ".xlsx":
+		workbook, err := excelize.OpenReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer workbook.Close()
+
+		rows, err = workbook.GetRows(workbook.GetSheetName(0))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("file must be .csv or .xlsx")
+	}
+
+	for i := range rows {
+		for j := range rows[i] {
+			rows[i][j] = strings.TrimSpace(strings.TrimPrefix(rows[i][j], "\ufeff"))
+		}
+	}
+
+	return rows, nil
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"mime/multipart"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -65,3 +67,22 @@ func ValidateImageFile(fileHeader *multipart.FileHeader) error {
 
 	return nil
 }
+
+var AllowedSpreadsheetExtensions = map[string]bool{
+	".csv":  true,
+	".xlsx": true,
+}
+
+const maxSpreadsheetSize = 5 * 1024 * 1024
+
+func ValidateSpreadsheetFile(fileHeader *multipart.FileHeader) error {
+	if fileHeader.Size > maxSpreadsheetSize {
+		return errors.New("file size exceeds the 5MB limit")
+	}
+
+	if !AllowedSpreadsheetExtensions[strings.ToLower(filepath.Ext(fileHeader.Filename))] {
+		return errors.New("file must be .csv or .xlsx")
+	}
+
+	return nil
+}
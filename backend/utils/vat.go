@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/BaimhonS/kab-phone/models"
+)
+
+const defaultVATRate = models.Rate(700) // 7.00 percent
+
+// GetVATConfig reads VAT_RATE (percent) and VAT_INCLUSIVE from the environment,
+// defaulting to 7 percent already included in shelf prices.
+func GetVATConfig() (models.Rate, bool) {
+	rate, err := models.ParseRate(os.Getenv("VAT_RATE"))
+	if err != nil || rate < 0 {
+		rate = defaultVATRate
+	}
+
+	inclusive, err := strconv.ParseBool(os.Getenv("VAT_INCLUSIVE"))
+	if err != nil {
+		inclusive = true
+	}
+
+	return rate, inclusive
+}
+
+// CalculateVAT splits amount into the price before VAT, the VAT and the amount the buyer pays.
+func CalculateVAT(amount models.Money, rate models.Rate, inclusive bool) (subtotal models.Money, vat models.Money, total models.Money) {
+	if inclusive {
+		vat = rate.IncludedIn(amount)
+		return amount - vat, vat, amount
+	}
+
+	vat = rate.Of(amount)
+	return amount, vat, amount + vat
+}
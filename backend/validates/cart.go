@@ -13,7 +13,7 @@ type (
 	}
 
 	RequestUpdateItemFromCart struct {
-		Amount int `json:"amount" validate:"required"`
+		Amount int `json:"amount" validate:"required,min=1"`
 	}
 
 	CartValidateImpl struct{}
@@ -45,6 +45,13 @@ func (v *CartValidateImpl) ValidateAddItemToCart(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.Item.PhoneID == 0 || req.Item.Amount < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "item phone_id and amount of at least 1 are required",
+			Error:   nil,
+		})
+	}
+
 	c.Locals("req", req)
 	return c.Next()
 }
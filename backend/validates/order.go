@@ -1,12 +1,221 @@
 package validates
 
+import (
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
 type (
+	RequestConfirmOrder struct {
+		ShippingCarrier string `json:"shipping_carrier" validate:"required,max=32"`
+		Province        string `json:"province" validate:"required,max=100"`
+	}
+
+	RequestPublicOrderTracking struct {
+		OrderNumber string `json:"order_number" validate:"required,max=32"`
+		PhoneNumber string `json:"phone_number" validate:"required,max=20"`
+	}
+
+	RequestUpdateOrderStatus struct {
+		Status string `json:"status" validate:"required,oneof=PAID PACKING DELIVERED"` // refunds go through the payment refund so stock and income follow
+		Note   string `json:"note" validate:"max=255"`
+	}
+
+	RequestAddTrackingNumber struct {
+		OrderID        uint   `json:"order_id" validate:"required"`
+		TrackingNumber string `json:"tracking_number" validate:"required,max=50"`
+		Carrier        string `json:"carrier" validate:"max=32"` // defaults to the carrier chosen at checkout
+	}
+
+	RequestCancelOrder struct {
+		Reason string `json:"reason" validate:"required,max=255"`
+	}
+
+	RequestRejectPaymentSlip struct {
+		Reason string `json:"reason" validate:"required,max=255"`
+	}
+
 	OrderValidateImpl struct{}
 )
 
 type OrderValidate interface {
+	ValidateConfirmOrder(c *fiber.Ctx) error
+	ValidatePublicOrderTracking(c *fiber.Ctx) error
+	ValidateUpdateOrderStatus(c *fiber.Ctx) error
+	ValidateAddTrackingNumber(c *fiber.Ctx) error
+	ValidateCancelOrder(c *fiber.Ctx) error
+	ValidateUploadPaymentSlip(c *fiber.Ctx) error
+	ValidateRejectPaymentSlip(c *fiber.Ctx) error
+	ValidateImportTrackingNumbers(c *fiber.Ctx) error
 }
 
 func NewOrderValidate() OrderValidate {
 	return &OrderValidateImpl{}
 }
+
+func (v *OrderValidateImpl) ValidateUpdateOrderStatus(c *fiber.Ctx) error {
+	var req RequestUpdateOrderStatus
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate update order status error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *OrderValidateImpl) ValidateAddTrackingNumber(c *fiber.Ctx) error {
+	var req RequestAddTrackingNumber
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate add tracking number error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *OrderValidateImpl) ValidateConfirmOrder(c *fiber.Ctx) error {
+	var req RequestConfirmOrder
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate confirm order error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *OrderValidateImpl) ValidatePublicOrderTracking(c *fiber.Ctx) error {
+	var req RequestPublicOrderTracking
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate public order tracking error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *OrderValidateImpl) ValidateCancelOrder(c *fiber.Ctx) error {
+	var req RequestCancelOrder
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate cancel order error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *OrderValidateImpl) ValidateUploadPaymentSlip(c *fiber.Ctx) error {
+	file, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "form file error",
+			Error:   err,
+		})
+	}
+
+	if err := utils.ValidateImageFile(file); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "validate file error",
+			Error:   err,
+		})
+	}
+
+	c.Locals("file", file)
+	return c.Next()
+}
+
+func (v *OrderValidateImpl) ValidateRejectPaymentSlip(c *fiber.Ctx) error {
+	var req RequestRejectPaymentSlip
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate reject payment slip error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *OrderValidateImpl) ValidateImportTrackingNumbers(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "form file error",
+			Error:   err,
+		})
+	}
+
+	if err := utils.ValidateSpreadsheetFile(file); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "validate file error",
+			Error:   err,
+		})
+	}
+
+	c.Locals("file", file)
+	return c.Next()
+}
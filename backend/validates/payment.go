@@ -0,0 +1,71 @@
+package validates
+
+import (
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	RequestCreatePayment struct {
+		OrderID  uint   `json:"order_id" validate:"required"`
+		Provider string `json:"provider" validate:"required,max=32"` // checked against the registered providers
+	}
+
+	RequestReceivePromptPay struct {
+		SlipRef string `json:"slip_ref" validate:"required,max=100"`
+	}
+
+	PaymentValidateImpl struct{}
+)
+
+type PaymentValidate interface {
+	ValidateCreatePayment(c *fiber.Ctx) error
+	ValidateReceivePromptPay(c *fiber.Ctx) error
+}
+
+func NewPaymentValidate() PaymentValidate {
+	return &PaymentValidateImpl{}
+}
+
+func (v *PaymentValidateImpl) ValidateCreatePayment(c *fiber.Ctx) error {
+	var req RequestCreatePayment
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate create payment error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *PaymentValidateImpl) ValidateReceivePromptPay(c *fiber.Ctx) error {
+	var req RequestReceivePromptPay
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate receive promptpay error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
@@ -1,6 +1,7 @@
 package validates
 
 import (
+	"github.com/BaimhonS/kab-phone/models"
 	"github.com/BaimhonS/kab-phone/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -8,18 +9,36 @@ import (
 
 type (
 	RequestCreatePhone struct {
-		ModelName string  `form:"model_name" validate:"required,min=3,max=50"`
-		BrandName string  `form:"brand_name" validate:"required,min=3,max=50"`
-		OS        string  `form:"os" validate:"required,min=3,max=50,alpha"`
-		Price     float32 `form:"price" validate:"required,min=0"`
-		Amount    int     `form:"amount" validate:"required,min=0"`
-		Image     []byte  `form:"image"`
+		ModelName    string       `form:"model_name" validate:"required,min=3,max=50"`
+		BrandName    string       `form:"brand_name" validate:"required,min=3,max=50"`
+		OS           string       `form:"os" validate:"required,min=3,max=50,alpha"`
+		Barcode      string       `form:"barcode" validate:"omitempty,max=64,printascii"`
+		Price        models.Money `form:"price" validate:"required,min=0"`
+		Amount       int          `form:"amount" validate:"required,min=0"`
+		ReorderPoint int          `form:"reorder_point" validate:"min=0"`
+		Weight       int          `form:"weight" validate:"min=0"`
+		Width        int          `form:"width" validate:"min=0"`
+		Height       int          `form:"height" validate:"min=0"`
+		Depth        int          `form:"depth" validate:"min=0"`
+		Image        []byte       `form:"image"`
 	}
 
 	RequestUpdatePhone struct {
-		Price  float32 `form:"price" validate:"min=0"`
-		Amount int     `form:"amount" validate:"min=0"`
-		Image  []byte  `form:"image"`
+		Price        models.Money `form:"price" validate:"min=0"`
+		Amount       int          `form:"amount" validate:"min=0"`
+		StockNote    string       `form:"stock_note" validate:"max=255"`            // why the amount changed , kept on the stock movement
+		ReorderPoint *int         `form:"reorder_point" validate:"omitempty,min=0"` // 0 turns low stock alerts off
+		Barcode      string       `form:"barcode" validate:"omitempty,max=64,printascii"`
+		Weight       int          `form:"weight" validate:"min=0"`
+		Width        int          `form:"width" validate:"min=0"`
+		Height       int          `form:"height" validate:"min=0"`
+		Depth        int          `form:"depth" validate:"min=0"`
+		Image        []byte       `form:"image"`
+	}
+
+	RequestAdjustStock struct {
+		Quantity int    `json:"quantity" validate:"required"` // signed , negative takes stock out
+		Reason   string `json:"reason" validate:"required,max=255"`
 	}
 
 	PhoneValidateImpl struct{}
@@ -28,6 +47,7 @@ type (
 type PhoneValidate interface {
 	ValidateCreatePhone(c *fiber.Ctx) error
 	ValidateUpdatePhone(c *fiber.Ctx) error
+	ValidateAdjustStock(c *fiber.Ctx) error
 }
 
 func NewPhoneValidate() PhoneValidate {
@@ -110,3 +130,24 @@ func (v *PhoneValidateImpl) ValidateUpdatePhone(c *fiber.Ctx) error {
 	c.Locals("req", req)
 	return c.Next()
 }
+
+func (v *PhoneValidateImpl) ValidateAdjustStock(c *fiber.Ctx) error {
+	var req RequestAdjustStock
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate adjust stock error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
@@ -0,0 +1,100 @@
+package validates
+
+import (
+	"fmt"
+
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	PhoneUnitInput struct {
+		IMEI   string `json:"imei" validate:"required,numeric,len=15"`
+		Serial string `json:"serial" validate:"max=64"`
+	}
+
+	RequestRegisterPhoneUnits struct {
+		Units []PhoneUnitInput `json:"units" validate:"required,min=1,max=500,dive"`
+	}
+
+	PhoneUnitAssignment struct {
+		ItemID uint   `json:"item_id" validate:"required"`
+		IMEI   string `json:"imei" validate:"required,numeric,len=15"`
+	}
+
+	RequestAssignPhoneUnits struct {
+		Assignments []PhoneUnitAssignment `json:"assignments" validate:"required,min=1,max=200,dive"`
+	}
+
+	PhoneUnitValidateImpl struct{}
+)
+
+type PhoneUnitValidate interface {
+	ValidateRegisterPhoneUnits(c *fiber.Ctx) error
+	ValidateAssignPhoneUnits(c *fiber.Ctx) error
+}
+
+func NewPhoneUnitValidate() PhoneUnitValidate {
+	return &PhoneUnitValidateImpl{}
+}
+
+func (v *PhoneUnitValidateImpl) ValidateRegisterPhoneUnits(c *fiber.Ctx) error {
+	var req RequestRegisterPhoneUnits
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate register phone units error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	for _, unit := range req.Units {
+		if !utils.ValidateIMEI(unit.IMEI) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("imei %s check digit invalid", unit.IMEI),
+				Error:   nil,
+			})
+		}
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *PhoneUnitValidateImpl) ValidateAssignPhoneUnits(c *fiber.Ctx) error {
+	var req RequestAssignPhoneUnits
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate assign phone units error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	for _, assignment := range req.Assignments {
+		if !utils.ValidateIMEI(assignment.IMEI) {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: fmt.Sprintf("imei %s check digit invalid", assignment.IMEI),
+				Error:   nil,
+			})
+		}
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
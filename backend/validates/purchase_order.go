@@ -0,0 +1,85 @@
+package validates
+
+import (
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	PurchaseOrderLineInput struct {
+		PhoneID          uint         `json:"phone_id" validate:"required"`
+		CostPrice        models.Money `json:"cost_price" validate:"min=0"`
+		ExpectedQuantity int          `json:"expected_quantity" validate:"required,min=1"`
+	}
+
+	// RequestSavePurchaseOrder creates a draft, or replaces the supplier , note and every line of one.
+	RequestSavePurchaseOrder struct {
+		SupplierID uint                     `json:"supplier_id" validate:"required"`
+		Note       string                   `json:"note" validate:"max=255"`
+		Lines      []PurchaseOrderLineInput `json:"lines" validate:"required,min=1,max=200,dive"`
+	}
+
+	PurchaseOrderReceiptInput struct {
+		LineID   uint `json:"line_id" validate:"required"`
+		Quantity int  `json:"quantity" validate:"required,min=1"`
+	}
+
+	RequestReceivePurchaseOrder struct {
+		Lines []PurchaseOrderReceiptInput `json:"lines" validate:"required,min=1,max=200,dive"`
+	}
+
+	PurchaseOrderValidateImpl struct{}
+)
+
+type PurchaseOrderValidate interface {
+	ValidateSavePurchaseOrder(c *fiber.Ctx) error
+	ValidateReceivePurchaseOrder(c *fiber.Ctx) error
+}
+
+func NewPurchaseOrderValidate() PurchaseOrderValidate {
+	return &PurchaseOrderValidateImpl{}
+}
+
+func (v *PurchaseOrderValidateImpl) ValidateSavePurchaseOrder(c *fiber.Ctx) error {
+	var req RequestSavePurchaseOrder
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate save purchase order error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *PurchaseOrderValidateImpl) ValidateReceivePurchaseOrder(c *fiber.Ctx) error {
+	var req RequestReceivePurchaseOrder
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate receive purchase order error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
@@ -0,0 +1,101 @@
+package validates
+
+import (
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	RequestCreateReturn struct {
+		ItemID     uint   `json:"item_id" validate:"required"`
+		Quantity   int    `json:"quantity" validate:"required,min=1"`
+		Reason     string `json:"reason" validate:"required,max=255"`
+		Condition  string `json:"condition" validate:"required,oneof=SEALED OPENED DAMAGED FAULTY"`
+		Resolution string `json:"resolution" validate:"required,oneof=REFUND REPLACE REPAIR"`
+	}
+
+	RequestReviewReturn struct {
+		Note string `json:"note" validate:"max=255"`
+	}
+
+	RequestReceiveReturn struct {
+		Restock bool     `json:"restock"`
+		IMEIs   []string `json:"imeis" validate:"max=100,dive,numeric,len=15"` // units that came back , when they were tracked
+	}
+
+	ReturnValidateImpl struct{}
+)
+
+type ReturnValidate interface {
+	ValidateCreateReturn(c *fiber.Ctx) error
+	ValidateReviewReturn(c *fiber.Ctx) error
+	ValidateReceiveReturn(c *fiber.Ctx) error
+}
+
+func NewReturnValidate() ReturnValidate {
+	return &ReturnValidateImpl{}
+}
+
+func (v *ReturnValidateImpl) ValidateCreateReturn(c *fiber.Ctx) error {
+	var req RequestCreateReturn
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate create return error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *ReturnValidateImpl) ValidateReviewReturn(c *fiber.Ctx) error {
+	var req RequestReviewReturn
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate review return error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *ReturnValidateImpl) ValidateReceiveReturn(c *fiber.Ctx) error {
+	var req RequestReceiveReturn
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate receive return error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
@@ -0,0 +1,103 @@
+package validates
+
+import (
+	"github.com/BaimhonS/kab-phone/models"
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	RequestCreateShippingCarrier struct {
+		Code                  string       `json:"code" validate:"required,alphanum,max=32"`
+		Name                  string       `json:"name" validate:"required,max=100"`
+		FreeShippingThreshold models.Money `json:"free_shipping_threshold" validate:"min=0"`
+	}
+
+	RequestUpdateShippingCarrier struct {
+		Name                  string        `json:"name" validate:"max=100"`
+		Active                *bool         `json:"active"`
+		FreeShippingThreshold *models.Money `json:"free_shipping_threshold" validate:"omitempty,min=0"`
+	}
+
+	RequestShippingRate struct {
+		Zone      string       `json:"zone" validate:"required,oneof=BANGKOK PROVINCE"`
+		MaxWeight int          `json:"max_weight" validate:"required,min=1"`
+		Fee       models.Money `json:"fee" validate:"min=0"`
+	}
+
+	ShippingValidateImpl struct{}
+)
+
+type ShippingValidate interface {
+	ValidateCreateShippingCarrier(c *fiber.Ctx) error
+	ValidateUpdateShippingCarrier(c *fiber.Ctx) error
+	ValidateShippingRate(c *fiber.Ctx) error
+}
+
+func NewShippingValidate() ShippingValidate {
+	return &ShippingValidateImpl{}
+}
+
+func (v *ShippingValidateImpl) ValidateCreateShippingCarrier(c *fiber.Ctx) error {
+	var req RequestCreateShippingCarrier
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate create shipping carrier error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *ShippingValidateImpl) ValidateUpdateShippingCarrier(c *fiber.Ctx) error {
+	var req RequestUpdateShippingCarrier
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate update shipping carrier error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *ShippingValidateImpl) ValidateShippingRate(c *fiber.Ctx) error {
+	var req RequestShippingRate
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate shipping rate error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
@@ -0,0 +1,136 @@
+package validates
+
+import (
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	RequestOpenStockTake struct {
+		Note string `json:"note" validate:"max=255"`
+	}
+
+	StockTakeCount struct {
+		PhoneID       uint `json:"phone_id" validate:"required"`
+		CountedAmount int  `json:"counted_amount" validate:"min=0"`
+	}
+
+	// RequestSubmitStockTakeCounts sets the counted amount of each listed phone , later submits overwrite earlier ones.
+	RequestSubmitStockTakeCounts struct {
+		Counts []StockTakeCount `json:"counts" validate:"required,min=1,max=500,dive"`
+	}
+
+	// RequestScanStockTake adds Quantity , 1 when left out , to the count of the phone with the barcode.
+	RequestScanStockTake struct {
+		Barcode  string `json:"barcode" validate:"required,max=64,printascii"`
+		Quantity int    `json:"quantity" validate:"min=0"`
+	}
+
+	RequestPostStockTake struct {
+		Reason string `json:"reason" validate:"required,max=255"`
+	}
+
+	StockTakeValidateImpl struct{}
+)
+
+type StockTakeValidate interface {
+	ValidateOpenStockTake(c *fiber.Ctx) error
+	ValidateSubmitStockTakeCounts(c *fiber.Ctx) error
+	ValidateScanStockTake(c *fiber.Ctx) error
+	ValidatePostStockTake(c *fiber.Ctx) error
+}
+
+func NewStockTakeValidate() StockTakeValidate {
+	return &StockTakeValidateImpl{}
+}
+
+func (v *StockTakeValidateImpl) ValidateOpenStockTake(c *fiber.Ctx) error {
+	var req RequestOpenStockTake
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+				Message: "body parser error",
+				Error:   err,
+			})
+		}
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate open stock take error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *StockTakeValidateImpl) ValidateSubmitStockTakeCounts(c *fiber.Ctx) error {
+	var req RequestSubmitStockTakeCounts
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate submit stock take counts error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *StockTakeValidateImpl) ValidateScanStockTake(c *fiber.Ctx) error {
+	var req RequestScanStockTake
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate scan stock take error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *StockTakeValidateImpl) ValidatePostStockTake(c *fiber.Ctx) error {
+	var req RequestPostStockTake
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate post stock take error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
@@ -0,0 +1,81 @@
+package validates
+
+import (
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	RequestCreateSupplier struct {
+		Name        string `json:"name" validate:"required,max=100"`
+		ContactName string `json:"contact_name" validate:"max=100"`
+		Email       string `json:"email" validate:"omitempty,email,max=100"`
+		PhoneNumber string `json:"phone_number" validate:"omitempty,numeric,max=20"`
+		Address     string `json:"address" validate:"max=255"`
+		TaxID       string `json:"tax_id" validate:"omitempty,numeric,len=13"`
+	}
+
+	RequestUpdateSupplier struct {
+		Name        string `json:"name" validate:"max=100"`
+		ContactName string `json:"contact_name" validate:"max=100"`
+		Email       string `json:"email" validate:"omitempty,email,max=100"`
+		PhoneNumber string `json:"phone_number" validate:"omitempty,numeric,max=20"`
+		Address     string `json:"address" validate:"max=255"`
+		TaxID       string `json:"tax_id" validate:"omitempty,numeric,len=13"`
+		Active      *bool  `json:"active"`
+	}
+
+	SupplierValidateImpl struct{}
+)
+
+type SupplierValidate interface {
+	ValidateCreateSupplier(c *fiber.Ctx) error
+	ValidateUpdateSupplier(c *fiber.Ctx) error
+}
+
+func NewSupplierValidate() SupplierValidate {
+	return &SupplierValidateImpl{}
+}
+
+func (v *SupplierValidateImpl) ValidateCreateSupplier(c *fiber.Ctx) error {
+	var req RequestCreateSupplier
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate create supplier error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *SupplierValidateImpl) ValidateUpdateSupplier(c *fiber.Ctx) error {
+	var req RequestUpdateSupplier
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate update supplier error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
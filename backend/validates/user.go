@@ -36,6 +36,8 @@ type (
 		Address     string    `json:"address" validate:"required,min=10,max=100"`
 		Age         int       `json:"age" validate:"required,min=1,max=150"`
 		BirthDate   time.Time `json:"birth_date" validate:"required"`
+		TaxID       string    `json:"tax_id" validate:"omitempty,len=13,numeric"`
+		TaxBranch   string    `json:"tax_branch" validate:"omitempty,len=5,numeric"`
 	}
 
 	UserValidateImpl struct{}
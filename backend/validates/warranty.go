@@ -0,0 +1,112 @@
+package validates
+
+import (
+	"github.com/BaimhonS/kab-phone/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	WarrantyTermInput struct {
+		Type   string `json:"type" validate:"required,oneof=MANUFACTURER SHOP"`
+		Months int    `json:"months" validate:"required,min=1,max=120"`
+	}
+
+	// RequestSetWarrantyTerms replaces every term of the phone , an empty list sells it without warranty.
+	RequestSetWarrantyTerms struct {
+		Terms []WarrantyTermInput `json:"terms" validate:"max=2,dive"`
+	}
+
+	RequestCreateWarrantyClaim struct {
+		Problem string `json:"problem" validate:"required,max=500"`
+		IMEI    string `json:"imei" validate:"omitempty,numeric,len=15"`
+	}
+
+	RequestUpdateWarrantyClaimStatus struct {
+		Status        string `json:"status" validate:"required,oneof=RECEIVED AT_SERVICE_CENTER RETURNED REJECTED"`
+		ServiceCenter string `json:"service_center" validate:"max=100"`
+		Note          string `json:"note" validate:"max=255"`
+	}
+
+	WarrantyValidateImpl struct{}
+)
+
+type WarrantyValidate interface {
+	ValidateSetWarrantyTerms(c *fiber.Ctx) error
+	ValidateCreateWarrantyClaim(c *fiber.Ctx) error
+	ValidateUpdateWarrantyClaimStatus(c *fiber.Ctx) error
+}
+
+func NewWarrantyValidate() WarrantyValidate {
+	return &WarrantyValidateImpl{}
+}
+
+func (v *WarrantyValidateImpl) ValidateSetWarrantyTerms(c *fiber.Ctx) error {
+	var req RequestSetWarrantyTerms
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate set warranty terms error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	if len(req.Terms) == 2 && req.Terms[0].Type == req.Terms[1].Type {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "each warranty type can only be listed once",
+			Error:   nil,
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *WarrantyValidateImpl) ValidateCreateWarrantyClaim(c *fiber.Ctx) error {
+	var req RequestCreateWarrantyClaim
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate create warranty claim error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}
+
+func (v *WarrantyValidateImpl) ValidateUpdateWarrantyClaimStatus(c *fiber.Ctx) error {
+	var req RequestUpdateWarrantyClaimStatus
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ErrorResponse{
+			Message: "body parser error",
+			Error:   err,
+		})
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ValidateErrorResponse{
+			Message: "validate update warranty claim status error",
+			Error:   utils.HanddleValidateError(err),
+		})
+	}
+
+	c.Locals("req", req)
+	return c.Next()
+}